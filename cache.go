@@ -3,72 +3,380 @@ package poeapi
 import (
 	"container/list"
 	"container/ring"
+	"encoding/json"
 	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
+// Cache stores API responses, keyed by the URL of the request. Custom
+// implementations may be set in ClientOptions, and must be safe for
+// concurrent use.
+type Cache interface {
+	// Get returns the cached value for key. ErrNotFoundInCache is returned
+	// if the key is missing or has expired.
+	Get(key string) (string, error)
+
+	// Set stores a value which expires after ttl. A zero ttl means the value
+	// does not expire, though the cache may still evict it.
+	Set(key, value string, ttl time.Duration) error
+
+	// Delete removes the value for key. Deleting a missing key is not an
+	// error.
+	Delete(key string) error
+}
+
+// CacheInvalidator is implemented by caches which can remove every value
+// whose key has a given URL path prefix. APIClient.Invalidate has no effect on
+// caches which do not implement it.
+type CacheInvalidator interface {
+	// Invalidate removes every value whose key has a URL path beginning
+	// with prefix, and returns the number removed. An empty prefix clears
+	// the cache.
+	Invalidate(prefix string) int
+}
+
+// CacheStats reports the activity of a cache.
+type CacheStats struct {
+	// Lookups which returned a response.
+	Hits int64
+
+	// Lookups which found no response, or an expired one.
+	Misses int64
+
+	// Responses removed to make room for others.
+	Evictions int64
+
+	// Responses which were not stored because they were too large.
+	Rejected int64
+
+	// The number of responses currently stored.
+	Entries int
+
+	// The size of the responses currently stored, in bytes.
+	Bytes int64
+
+	// Calls which shared the in-flight request of an identical concurrent
+	// call rather than sending their own. This is counted by the client, not
+	// the cache, so it is reported whichever cache is used, and when caching
+	// is disabled.
+	Coalesced int64
+}
+
+// CacheStatsReporter is implemented by caches which track their activity.
+// APIClient.CacheStats returns the zero CacheStats for caches which do not
+// implement it.
+type CacheStatsReporter interface {
+	Stats() CacheStats
+}
+
+// MemoryCacheOptions contains settings for an in-memory cache. At least one of
+// MaxEntries and MaxBytes must be set.
+type MemoryCacheOptions struct {
+	// The maximum number of responses to store. Zero means no limit.
+	MaxEntries int
+
+	// The maximum total size of stored responses, counting both URLs and
+	// bodies, in bytes. Zero means no limit.
+	MaxBytes int64
+
+	// The maximum size of a single response. Larger responses are not
+	// stored. Zero means responses may be as large as MaxBytes.
+	MaxResponseBytes int64
+}
+
+// NewMemoryCache returns an in-memory Cache which evicts the least recently
+// used responses when it exceeds its limits. This is the cache used by
+// default.
+func NewMemoryCache(opts MemoryCacheOptions) (Cache, error) {
+	return newResponseCache(opts)
+}
+
 // responsecache stores JSON responses from the API, storing them by URL. It is
 // thread-safe and uses strings for both keys and values. It tracks recently
-// used URLs deletes the oldest entries when maxSize is reached.
+// used URLs and deletes the oldest entries when the number or total size of
+// the responses exceeds its limits. Entries may also expire, after which they
+// are treated as missing.
 type responsecache struct {
 	responses  map[string]*list.Element
 	recenturls *list.List
-	maxSize    int
+	opts       MemoryCacheOptions
+	stats      CacheStats
 	lock       sync.Mutex
+
+	// now returns the current time, and is replaced in tests.
+	now func() time.Time
 }
 
 type response struct {
-	url  string
-	body string
+	url     string
+	body    string
+	expires time.Time
 }
 
-// Get retrieves a response from the cache.
+// size returns the number of bytes a response counts towards the cache's
+// limit.
+func (r *response) size() int64 {
+	return int64(len(r.url) + len(r.body))
+}
+
+// Get retrieves a response from the cache. Expired responses are removed and
+// reported as missing.
 func (c *responsecache) Get(url string) (string, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	resp, ok := c.responses[url]
 	if !ok {
+		c.stats.Misses++
+		return "", ErrNotFoundInCache
+	}
+	r := resp.Value.(*response)
+	if !r.expires.IsZero() && !c.now().Before(r.expires) {
+		c.remove(resp)
+		c.stats.Misses++
 		return "", ErrNotFoundInCache
 	}
 
 	c.recenturls.MoveToFront(resp)
-	return resp.Value.(*response).body, nil
+	c.stats.Hits++
+	return r.body, nil
 }
 
-// Set writes a response to the cache.
-func (c *responsecache) Set(url, body string) {
+// Set writes a response to the cache which expires after ttl. A zero ttl
+// means the response does not expire, though it may still be evicted.
+// ErrCacheEntryTooLarge is returned for responses above MaxResponseBytes, and
+// any previous response for the URL is removed.
+func (c *responsecache) Set(url, body string, ttl time.Duration) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	r := &response{url: url, body: body}
+	if ttl > 0 {
+		r.expires = c.now().Add(ttl)
+	}
+	if old, ok := c.responses[url]; ok {
+		c.remove(old)
+	}
+	if c.tooLarge(r.size()) {
+		c.stats.Rejected++
+		return ErrCacheEntryTooLarge
+	}
+
+	c.responses[url] = c.recenturls.PushFront(r)
+	c.stats.Entries++
+	c.stats.Bytes += r.size()
+
+	for c.full() {
+		c.remove(c.recenturls.Back())
+		c.stats.Evictions++
+	}
+	return nil
+}
+
+// tooLarge reports whether a response of the given size may not be stored.
+func (c *responsecache) tooLarge(size int64) bool {
+	if c.opts.MaxResponseBytes > 0 && size > c.opts.MaxResponseBytes {
+		return true
+	}
+	return c.opts.MaxBytes > 0 && size > c.opts.MaxBytes
+}
+
+// full reports whether the cache exceeds its limits.
+func (c *responsecache) full() bool {
+	if c.opts.MaxEntries > 0 && c.stats.Entries > c.opts.MaxEntries {
+		return true
+	}
+	return c.opts.MaxBytes > 0 && c.stats.Bytes > c.opts.MaxBytes
+}
+
+// Delete removes a response from the cache.
+func (c *responsecache) Delete(url string) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
 	if resp, ok := c.responses[url]; ok {
-		c.recenturls.MoveToFront(resp)
-		resp.Value.(*response).body = body
-		return
+		c.remove(resp)
 	}
-	c.responses[url] = c.recenturls.PushFront(&response{
-		url:  url,
-		body: body,
-	})
+	return nil
+}
 
-	if c.recenturls.Len() > c.maxSize {
-		oldest := c.recenturls.Back()
-		c.recenturls.Remove(oldest)
-		delete(c.responses, oldest.Value.(*response).url)
+// Invalidate removes every response whose URL path begins with prefix, such
+// as "/leagues", and returns the number removed. An empty prefix clears the
+// cache.
+func (c *responsecache) Invalidate(prefix string) int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	removed := 0
+	for key, resp := range c.responses {
+		if strings.HasPrefix(cachePath(key), prefix) {
+			c.remove(resp)
+			removed++
+		}
 	}
+	return removed
+}
+
+// Stats returns the cache's activity since it was created.
+func (c *responsecache) Stats() CacheStats {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.stats
+}
+
+func (c *responsecache) remove(resp *list.Element) {
+	r := resp.Value.(*response)
+	c.recenturls.Remove(resp)
+	delete(c.responses, r.url)
+	c.stats.Entries--
+	c.stats.Bytes -= r.size()
 }
 
-func newResponseCache(maxSize int) (*responsecache, error) {
-	if maxSize < 1 {
+func newResponseCache(opts MemoryCacheOptions) (*responsecache, error) {
+	if opts.MaxEntries < 0 || opts.MaxBytes < 0 || opts.MaxResponseBytes < 0 {
+		return nil, ErrInvalidCacheSize
+	}
+	if opts.MaxEntries == 0 && opts.MaxBytes == 0 {
 		return nil, ErrInvalidCacheSize
 	}
 	return &responsecache{
 		responses:  make(map[string]*list.Element),
 		recenturls: list.New(),
-		maxSize:    maxSize,
+		opts:       opts,
+		now:        time.Now,
 	}, nil
 }
 
+// cacheEnvelopePrefix marks cached values which hold a cacheEntry rather
+// than a bare response body. JSON bodies never begin with it.
+const cacheEnvelopePrefix = "#poeapi "
+
+// cacheEntry is a cached response along with its validators, which allow a
+// stale response to be revalidated with a conditional request rather than
+// fetched again in full.
+type cacheEntry struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+
+	// The time at which the response becomes stale, in Unix nanoseconds.
+	// Zero means the response does not become stale.
+	Expires int64 `json:"expires,omitempty"`
+
+	Body string `json:"-"`
+}
+
+// revalidatable reports whether the entry has validators.
+func (e cacheEntry) revalidatable() bool {
+	return e.ETag != "" || e.LastModified != ""
+}
+
+// fresh reports whether the entry may be used without revalidation.
+func (e cacheEntry) fresh(now time.Time) bool {
+	return e.Expires == 0 || now.Before(time.Unix(0, e.Expires))
+}
+
+// encode stores the entry as a single line of JSON followed by the body.
+func (e cacheEntry) encode() string {
+	meta, _ := json.Marshal(e)
+	return cacheEnvelopePrefix + string(meta) + "\n" + e.Body
+}
+
+// decodeCacheEntry reads a value written by cacheEntry.encode. Other values,
+// such as those written by earlier versions, are treated as fresh bodies.
+func decodeCacheEntry(value string) cacheEntry {
+	if !strings.HasPrefix(value, cacheEnvelopePrefix) {
+		return cacheEntry{Body: value}
+	}
+	n := strings.IndexByte(value, '\n')
+	if n < 0 {
+		return cacheEntry{Body: value}
+	}
+	var e cacheEntry
+	if err := json.Unmarshal([]byte(value[len(cacheEnvelopePrefix):n]), &e); err != nil {
+		return cacheEntry{Body: value}
+	}
+	e.Body = value[n+1:]
+	return e
+}
+
+// cacheNoStore reports whether the response's Cache-Control header forbids
+// storing it at all. Responses marked no-cache may be stored, but must be
+// revalidated before each use.
+func cacheNoStore(header http.Header) bool {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		if strings.ToLower(strings.TrimSpace(directive)) == "no-store" {
+			return true
+		}
+	}
+	return false
+}
+
+// cachePath returns the path of a cache key, which is the URL of the request.
+func cachePath(key string) string {
+	u, err := url.Parse(key)
+	if err != nil {
+		return key
+	}
+	return u.Path
+}
+
+// cacheTTL returns how long a response may be cached according to its
+// Cache-Control and Expires headers. The second result is false when the
+// headers say nothing about caching, and the TTL is zero when the response
+// must not be cached.
+func cacheTTL(header http.Header, now time.Time) (time.Duration, bool) {
+	if cc := header.Get("Cache-Control"); cc != "" {
+		maxAge, found := time.Duration(0), false
+		for _, directive := range strings.Split(cc, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			switch {
+			case directive == "no-store" || directive == "no-cache":
+				return 0, true
+			case strings.HasPrefix(directive, "max-age="):
+				n, err := strconv.Atoi(strings.Trim(directive[len("max-age="):], `"`))
+				if err != nil {
+					// An invalid max-age means the response is stale.
+					return 0, true
+				}
+				maxAge, found = time.Duration(n)*time.Second, true
+			}
+		}
+		if found {
+			// Age reports how long the response has already spent in
+			// shared caches.
+			if age, err := strconv.Atoi(header.Get("Age")); err == nil && age > 0 {
+				maxAge -= time.Duration(age) * time.Second
+			}
+			if maxAge < 0 {
+				maxAge = 0
+			}
+			return maxAge, true
+		}
+	}
+
+	if exp := header.Get("Expires"); exp != "" {
+		expires, err := http.ParseTime(exp)
+		if err != nil {
+			// Invalid dates, such as "0", mean the response has expired.
+			return 0, true
+		}
+		// Measure from the server's Date where possible, to avoid clock skew.
+		if date, err := http.ParseTime(header.Get("Date")); err == nil {
+			now = date
+		}
+		if ttl := expires.Sub(now); ttl > 0 {
+			return ttl, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
 // dnscache is an in-memory ring cache which caches IP addresses from DNS
 // resolution for api.pathofexile.com. DNS can be a significant factor in
 // request latency, and Go does not cache DNS resolution by default.
@@ -2,39 +2,40 @@ package poeapi
 
 import (
 	"container/ring"
+	"net/http"
 	"testing"
 	"time"
 )
 
 func TestNewResponseCache(t *testing.T) {
-	_, err := newResponseCache(1)
+	_, err := newResponseCache(MemoryCacheOptions{MaxEntries: 1})
 	if err != nil {
 		t.Fatalf("failed to create cache: %v", err)
 	}
 }
 
 func TestInvalidCacheSize(t *testing.T) {
-	_, err := newResponseCache(0)
+	_, err := newResponseCache(MemoryCacheOptions{})
 	if err != ErrInvalidCacheSize {
 		t.Fatal("failed to detect invalid cache size")
 	}
 }
 
 func TestCacheEviction(t *testing.T) {
-	cache, err := newResponseCache(10)
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
 	if err != nil {
 		t.Fatalf("failed to create cache for operations test: %v", err)
 	}
 
-	cache.Set("1", "A")
-	cache.Set("2", "B")
-	cache.Set("3", "C")
-	cache.Set("4", "D")
-	cache.Set("5", "E")
-	cache.Set("6", "F")
-	cache.Set("7", "G")
-	cache.Set("8", "H")
-	cache.Set("9", "I")
+	cache.Set("1", "A", 0)
+	cache.Set("2", "B", 0)
+	cache.Set("3", "C", 0)
+	cache.Set("4", "D", 0)
+	cache.Set("5", "E", 0)
+	cache.Set("6", "F", 0)
+	cache.Set("7", "G", 0)
+	cache.Set("8", "H", 0)
+	cache.Set("9", "I", 0)
 
 	val, err := cache.Get("5")
 	if err != nil {
@@ -44,8 +45,8 @@ func TestCacheEviction(t *testing.T) {
 		t.Fatalf("unexpected cache result: got %s, expected E", val)
 	}
 
-	cache.Set("foo", "foo")
-	cache.Set("bar", "bar")
+	cache.Set("foo", "foo", 0)
+	cache.Set("bar", "bar", 0)
 
 	_, err = cache.Get("1")
 	if err != ErrNotFoundInCache {
@@ -86,12 +87,226 @@ func TestCacheLatency(t *testing.T) {
 }
 
 func TestCacheExistingKey(t *testing.T) {
-	cache, err := newResponseCache(10)
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
 	if err != nil {
 		t.Fatalf("failed to create cache for existing key test: %v", err)
 	}
-	cache.Set("foo", "bar")
-	cache.Set("foo", "bar")
+	cache.Set("foo", "bar", 0)
+	cache.Set("foo", "bar", 0)
+}
+
+func TestCacheByteEviction(t *testing.T) {
+	// Each response counts the size of its URL and body: 1 + 9 bytes.
+	cache, err := newResponseCache(MemoryCacheOptions{MaxBytes: 30})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	cache.Set("1", "AAAAAAAAA", 0)
+	cache.Set("2", "BBBBBBBBB", 0)
+	cache.Set("3", "CCCCCCCCC", 0)
+	if _, err := cache.Get("1"); err != nil {
+		t.Fatalf("failed to get from cache: %v", err)
+	}
+	cache.Set("4", "DDDDDDDDD", 0)
+
+	if _, err := cache.Get("2"); err != ErrNotFoundInCache {
+		t.Fatal("failed to evict least recently used response")
+	}
+	stats := cache.Stats()
+	if stats.Entries != 3 || stats.Bytes != 30 || stats.Evictions != 1 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+
+	// A large response evicts several small ones.
+	cache.Set("5", "EEEEEEEEEEEEEEEEEEE", 0)
+	stats = cache.Stats()
+	if stats.Entries != 2 || stats.Bytes != 30 || stats.Evictions != 3 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+	if stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+}
+
+func TestCacheReplaceAccounting(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{MaxBytes: 100})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	cache.Set("foo", "barbarbar", 0)
+	cache.Set("foo", "bar", 0)
+	if stats := cache.Stats(); stats.Entries != 1 || stats.Bytes != 6 {
+		t.Fatalf("unexpected cache stats after replacement: %+v", stats)
+	}
+	cache.Delete("foo")
+	if stats := cache.Stats(); stats.Entries != 0 || stats.Bytes != 0 {
+		t.Fatalf("unexpected cache stats after deletion: %+v", stats)
+	}
+}
+
+func TestCacheRejectsLargeResponse(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{
+		MaxBytes:         100,
+		MaxResponseBytes: 10,
+	})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	cache.Set("foo", "bar", 0)
+	if err := cache.Set("foo", "barbarbar", 0); err != ErrCacheEntryTooLarge {
+		t.Fatal("failed to reject large response")
+	}
+	if _, err := cache.Get("foo"); err != ErrNotFoundInCache {
+		t.Fatal("failed to remove replaced response")
+	}
+	if stats := cache.Stats(); stats.Rejected != 1 || stats.Entries != 0 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+
+	// Without MaxResponseBytes, responses larger than the cache are rejected.
+	cache, err = newResponseCache(MemoryCacheOptions{MaxBytes: 10})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	if err := cache.Set("foo", "barbarbar", 0); err != ErrCacheEntryTooLarge {
+		t.Fatal("failed to reject response larger than cache")
+	}
+}
+
+func TestInvalidMemoryCacheOptions(t *testing.T) {
+	cases := []MemoryCacheOptions{
+		{MaxEntries: -1, MaxBytes: 100},
+		{MaxBytes: -1},
+		{MaxBytes: 100, MaxResponseBytes: -1},
+	}
+	for _, opts := range cases {
+		if _, err := NewMemoryCache(opts); err != ErrInvalidCacheSize {
+			t.Fatalf("failed to detect invalid cache options: %+v", opts)
+		}
+	}
+}
+
+func TestClientCacheStats(t *testing.T) {
+	opts := DefaultClientOptions
+	opts.Host = testHost
+	opts.UseSSL = false
+	opts.UseDNSCache = false
+	opts.RateLimit = UnlimitedRate
+	opts.StashRateLimit = UnlimitedRate
+	opts.RequestTimeout = testTimeout
+
+	c, err := NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetLeague(GetLeagueOptions{ID: "Standard"}); err != nil {
+			t.Fatalf("failed to get league: %v", err)
+		}
+	}
+	stats := c.CacheStats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Entries != 1 || stats.Bytes == 0 {
+		t.Fatalf("unexpected cache stats: %+v", stats)
+	}
+
+	opts.UseCache = false
+	c, err = NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if stats := c.CacheStats(); stats != (CacheStats{}) {
+		t.Fatalf("unexpected cache stats without cache: %+v", stats)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("failed to create cache for expiry test: %v", err)
+	}
+	now := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	cache.now = func() time.Time { return now }
+
+	cache.Set("foo", "bar", time.Minute)
+	cache.Set("baz", "qux", 0)
+
+	now = now.Add(59 * time.Second)
+	if _, err := cache.Get("foo"); err != nil {
+		t.Fatalf("failed to get unexpired response: %v", err)
+	}
+	now = now.Add(time.Second)
+	if _, err := cache.Get("foo"); err != ErrNotFoundInCache {
+		t.Fatal("failed to expire response")
+	}
+	if _, err := cache.Get("baz"); err != nil {
+		t.Fatalf("failed to get response without ttl: %v", err)
+	}
+	if n := cache.recenturls.Len(); n != 1 {
+		t.Fatalf("failed to remove expired response: %d entries remain", n)
+	}
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("failed to create cache for invalidation test: %v", err)
+	}
+	cache.Set("https://api.pathofexile.com/leagues", "A", 0)
+	cache.Set("https://api.pathofexile.com/leagues/Standard", "B", 0)
+	cache.Set("https://api.pathofexile.com/ladders/Standard?limit=200", "C", 0)
+	cache.Set("https://www.pathofexile.com/api/trade/search/Standard#abc", "D", 0)
+
+	if n := cache.Invalidate("/leagues"); n != 2 {
+		t.Fatalf("unexpected invalidation count: expected 2, got %d", n)
+	}
+	if _, err := cache.Get("https://api.pathofexile.com/leagues"); err != ErrNotFoundInCache {
+		t.Fatal("failed to invalidate response")
+	}
+	if _, err := cache.Get("https://api.pathofexile.com/ladders/Standard?limit=200"); err != nil {
+		t.Fatalf("invalidated unrelated response: %v", err)
+	}
+	if n := cache.Invalidate("/api/trade/search"); n != 1 {
+		t.Fatalf("unexpected invalidation count: expected 1, got %d", n)
+	}
+	if n := cache.Invalidate(""); n != 1 {
+		t.Fatalf("unexpected invalidation count: expected 1, got %d", n)
+	}
+}
+
+func TestCacheTTL(t *testing.T) {
+	now := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	cases := []struct {
+		header http.Header
+		ttl    time.Duration
+		ok     bool
+	}{
+		{http.Header{}, 0, false},
+		{http.Header{"Cache-Control": {"public, max-age=300"}}, 5 * time.Minute, true},
+		{http.Header{"Cache-Control": {"max-age=300"}, "Age": {"100"}}, 200 * time.Second, true},
+		{http.Header{"Cache-Control": {"max-age=60"}, "Age": {"100"}}, 0, true},
+		{http.Header{"Cache-Control": {"no-store"}}, 0, true},
+		{http.Header{"Cache-Control": {"max-age=300, no-cache"}}, 0, true},
+		{http.Header{"Cache-Control": {"max-age=abc"}}, 0, true},
+		{http.Header{"Cache-Control": {"private"}}, 0, false},
+		{http.Header{
+			"Cache-Control": {"max-age=60"},
+			"Expires":       {"Sun, 20 Aug 2023 13:00:00 GMT"},
+		}, time.Minute, true},
+		{http.Header{"Expires": {"Sun, 20 Aug 2023 12:30:00 GMT"}}, 30 * time.Minute, true},
+		{http.Header{
+			"Expires": {"Sun, 20 Aug 2023 12:30:00 GMT"},
+			"Date":    {"Sun, 20 Aug 2023 12:20:00 GMT"},
+		}, 10 * time.Minute, true},
+		{http.Header{"Expires": {"Sun, 20 Aug 2023 11:00:00 GMT"}}, 0, true},
+		{http.Header{"Expires": {"0"}}, 0, true},
+	}
+	for _, c := range cases {
+		ttl, ok := cacheTTL(c.header, now)
+		if ttl != c.ttl || ok != c.ok {
+			t.Fatalf("unexpected ttl for %v: expected %v %v, got %v %v",
+				c.header, c.ttl, c.ok, ttl, ok)
+		}
+	}
 }
 
 func TestDNSCacheResolve(t *testing.T) {
@@ -0,0 +1,198 @@
+package poeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// mainInventoryID is the inventory ID of items which are carried in a
+// character's inventory rather than equipped.
+const mainInventoryID = "MainInventory"
+
+// GetCharacterOptions contains the request parameters for the character
+// endpoint. Name is required, and Account and Realm are optional. Without an
+// Account, the character must belong to the account which granted the OAuth
+// token, which requires the ScopeAccountCharacters scope.
+type GetCharacterOptions struct {
+	// The name of the character to retrieve.
+	Name string
+
+	// The name of another account which owns the character, such as
+	// "Player#1234". No OAuth token is needed, but the account's characters
+	// tab must be public, or ErrForbidden is returned. Jewels socketed in the
+	// passive tree are not included for these characters.
+	Account string
+
+	// The realm of the character.
+	// Valid options: 'pc', 'xbox', or 'sony'.
+	Realm string
+}
+
+func validateGetCharacterOptions(opts GetCharacterOptions) error {
+	if opts.Name == "" {
+		return ErrInvalidCharacterName
+	}
+	if opts.Realm != "" {
+		if _, ok := validRealms[opts.Realm]; !ok {
+			return ErrInvalidRealm
+		}
+	}
+	return nil
+}
+
+func (c *client) GetCharacter(opts GetCharacterOptions) (Character, error) {
+	return c.GetCharacterContext(context.Background(), opts)
+}
+
+func (c *client) GetCharacterContext(ctx context.Context, opts GetCharacterOptions) (Character, error) {
+	if err := validateGetCharacterOptions(opts); err != nil {
+		return Character{}, err
+	}
+	if opts.Account != "" {
+		return c.getPublicCharacter(ctx, opts)
+	}
+	resp, err := c.get(ctx, fmt.Sprintf("%s/%s", c.charactersPath(opts.Realm),
+		url.PathEscape(opts.Name)))
+	if err != nil {
+		return Character{}, err
+	}
+	return parseCharacterResponse(resp)
+}
+
+func (c *client) GetPassives(opts GetCharacterOptions) (Passives, error) {
+	return c.GetPassivesContext(context.Background(), opts)
+}
+
+func (c *client) GetPassivesContext(ctx context.Context, opts GetCharacterOptions) (Passives, error) {
+	character, err := c.GetCharacterContext(ctx, opts)
+	if err != nil {
+		return Passives{}, err
+	}
+	if opts.Account != "" {
+		if character.Passives, err = c.getPublicPassives(ctx, opts); err != nil {
+			return Passives{}, err
+		}
+	}
+	if character.Passives == nil {
+		return Passives{Ascendancy: character.Class}, nil
+	}
+	passives := *character.Passives
+	passives.Ascendancy = character.Class
+	return passives, nil
+}
+
+type characterResponse struct {
+	Character Character `json:"character"`
+}
+
+func parseCharacterResponse(resp string) (Character, error) {
+	var r characterResponse
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return Character{}, err
+	}
+	return r.Character, nil
+}
+
+// getPublicCharacter retrieves another account's character and its items from
+// the legacy character window endpoint.
+func (c *client) getPublicCharacter(ctx context.Context, opts GetCharacterOptions) (Character, error) {
+	realm := publicRealm(opts.Realm)
+	resp, err := c.get(ctx, c.characterWindowURL(publicItemsEndpoint, opts.Account,
+		opts.Name, realm))
+	if err != nil {
+		return Character{}, err
+	}
+	return parsePublicItemsResponse(resp, realm)
+}
+
+// publicItemsResponse lists a character's items, both equipped and in its
+// inventory, without distinguishing them other than by inventory ID.
+type publicItemsResponse struct {
+	Items     []Item          `json:"items"`
+	Character publicCharacter `json:"character"`
+}
+
+func parsePublicItemsResponse(resp, realm string) (Character, error) {
+	var r publicItemsResponse
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return Character{}, err
+	}
+	character := Character{
+		Name:       r.Character.Name,
+		Level:      r.Character.Level,
+		Class:      r.Character.Class,
+		Experience: r.Character.Experience,
+		Realm:      realm,
+		League:     r.Character.League,
+	}
+	for _, item := range r.Items {
+		if item.InventoryID == mainInventoryID {
+			character.Inventory = append(character.Inventory, item)
+		} else {
+			character.Equipment = append(character.Equipment, item)
+		}
+	}
+	return character, nil
+}
+
+// getPublicPassives retrieves the passive skill allocations of another
+// account's character from the legacy character window endpoint.
+func (c *client) getPublicPassives(ctx context.Context, opts GetCharacterOptions) (*Passives, error) {
+	resp, err := c.get(ctx, c.characterWindowURL(publicPassivesEndpoint, opts.Account,
+		opts.Name, publicRealm(opts.Realm)))
+	if err != nil {
+		return nil, err
+	}
+	return parsePublicPassivesResponse(resp)
+}
+
+// publicPassivesResponse uses the same fields as Passives, except for the
+// encoding of mastery effects.
+type publicPassivesResponse struct {
+	Passives
+	MasteryEffects json.RawMessage `json:"mastery_effects"`
+}
+
+func parsePublicPassivesResponse(resp string) (*Passives, error) {
+	var r publicPassivesResponse
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return nil, err
+	}
+	effects, err := parseMasteryEffects(r.MasteryEffects)
+	if err != nil {
+		return nil, err
+	}
+	passives := r.Passives
+	passives.MasteryEffects = effects
+	return &passives, nil
+}
+
+// parseMasteryEffects decodes mastery effects, which the legacy endpoint packs
+// into one number per mastery with the effect hash in the upper 16 bits and
+// the mastery hash in the lower 16 bits. Effects keyed by mastery hash, as
+// returned by the OAuth API, are also accepted.
+func parseMasteryEffects(raw json.RawMessage) (map[string]int, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	var keyed map[string]int
+	if err := json.Unmarshal(raw, &keyed); err == nil {
+		return keyed, nil
+	}
+	var packed []json.Number
+	if err := json.Unmarshal(raw, &packed); err != nil {
+		return nil, err
+	}
+	effects := make(map[string]int, len(packed))
+	for _, p := range packed {
+		n, err := strconv.ParseInt(p.String(), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		effects[strconv.FormatInt(n&0xffff, 10)] = int(n >> 16)
+	}
+	return effects, nil
+}
@@ -0,0 +1,201 @@
+package poeapi
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestGetCharacter(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	character, err := c.GetCharacter(GetCharacterOptions{Name: "Character1"})
+	if err != nil {
+		t.Fatalf("failed to get character: %v", err)
+	}
+	if character.Name != "Character1" {
+		t.Fatalf("unexpected character name: %s", character.Name)
+	}
+}
+
+func TestGetCharacterWithInvalidOptions(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	if _, err := c.GetCharacter(GetCharacterOptions{}); err != ErrInvalidCharacterName {
+		t.Fatal("failed to detect missing character name")
+	}
+}
+
+func TestGetCharacterWithRequestFailure(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	_, err := c.GetCharacter(GetCharacterOptions{Name: "Nonexistent"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("failed to detect character request failure")
+	}
+}
+
+func TestGetPassives(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	passives, err := c.GetPassives(GetCharacterOptions{Name: "Character1"})
+	if err != nil {
+		t.Fatalf("failed to get passives: %v", err)
+	}
+	if passives.Ascendancy != "Inquisitor" || passives.BanditChoice != "Kraityn" {
+		t.Fatalf("unexpected passives: %+v", passives)
+	}
+	if passives.JewelData["0"].Type != "JewelInt" {
+		t.Fatal("failed to parse jewel data")
+	}
+}
+
+func TestParseCharacterResponse(t *testing.T) {
+	resp, err := loadFixture("fixtures/character.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	character, err := parseCharacterResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse character response: %v", err)
+	}
+	if len(character.Equipment) != 1 || character.Equipment[0].Name != "Kaom's Heart" {
+		t.Fatalf("unexpected equipment: %+v", character.Equipment)
+	}
+	if len(character.Equipment[0].Sockets) != 3 {
+		t.Fatal("failed to parse equipment sockets")
+	}
+	if len(character.Inventory) != 1 || len(character.Jewels) != 1 {
+		t.Fatal("failed to parse inventory and jewels")
+	}
+	if character.Passives == nil || len(character.Passives.Hashes) != 5 {
+		t.Fatal("failed to parse passives")
+	}
+	if character.Passives.MasteryEffects["33631"] != 48385 {
+		t.Fatal("failed to parse mastery effects")
+	}
+}
+
+func TestParseCharacterResponseWithInvalidJSON(t *testing.T) {
+	resp, err := loadFixture("fixtures/invalid.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if _, err := parseCharacterResponse(resp); err == nil {
+		t.Fatal("failed to detect invalid json")
+	}
+}
+
+// publicCharacterClient returns a client which serves the character window
+// endpoints from fixtures, and records the URLs it requests.
+func publicCharacterClient(t *testing.T, urls *[]string) *client {
+	fixtures := map[string]string{
+		publicItemsEndpoint:    "fixtures/public_items.json",
+		publicPassivesEndpoint: "fixtures/public_passives.json",
+	}
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		*urls = append(*urls, req.URL.String())
+		body, err := loadFixture(fixtures[req.URL.Path])
+		if err != nil {
+			t.Fatalf("failed to load fixture: %v", err)
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	return &client{
+		host:       DefaultHost,
+		tradeHost:  DefaultTradeHost,
+		useSSL:     true,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: &http.Client{Transport: transport},
+	}
+}
+
+func TestGetCharacterWithAccount(t *testing.T) {
+	var urls []string
+	c := publicCharacterClient(t, &urls)
+
+	character, err := c.GetCharacter(GetCharacterOptions{
+		Name:    "Character3",
+		Account: "Player#1234",
+		Realm:   "xbox",
+	})
+	if err != nil {
+		t.Fatalf("failed to get character for account: %v", err)
+	}
+	expected := "https://www.pathofexile.com/character-window/get-items" +
+		"?accountName=Player%231234&character=Character3&realm=xbox"
+	if len(urls) != 1 || urls[0] != expected {
+		t.Fatalf("unexpected request urls: %v", urls)
+	}
+	if character.Class != "Berserker" || character.Realm != "xbox" {
+		t.Fatalf("unexpected character: %+v", character)
+	}
+	if len(character.Equipment) != 1 || character.Equipment[0].Name != "Kaom's Heart" {
+		t.Fatalf("unexpected equipment: %+v", character.Equipment)
+	}
+	if len(character.Inventory) != 1 || character.Inventory[0].TypeLine != "Chaos Orb" {
+		t.Fatalf("unexpected inventory: %+v", character.Inventory)
+	}
+}
+
+func TestGetPassivesWithAccount(t *testing.T) {
+	var urls []string
+	c := publicCharacterClient(t, &urls)
+
+	passives, err := c.GetPassives(GetCharacterOptions{Name: "Character3", Account: "Player#1234"})
+	if err != nil {
+		t.Fatalf("failed to get passives for account: %v", err)
+	}
+	expected := "https://www.pathofexile.com/character-window/get-passive-skills" +
+		"?accountName=Player%231234&character=Character3&realm=pc"
+	if len(urls) != 2 || urls[1] != expected {
+		t.Fatalf("unexpected request urls: %v", urls)
+	}
+	if passives.Ascendancy != "Berserker" || len(passives.Hashes) != 3 {
+		t.Fatalf("unexpected passives: %+v", passives)
+	}
+	if passives.MasteryEffects["15215"] != 48401 || passives.MasteryEffects["8143"] != 64239 {
+		t.Fatalf("failed to unpack mastery effects: %v", passives.MasteryEffects)
+	}
+}
+
+func TestParseMasteryEffects(t *testing.T) {
+	effects, err := parseMasteryEffects([]byte(`{"33631":48385}`))
+	if err != nil || effects["33631"] != 48385 {
+		t.Fatalf("failed to parse keyed mastery effects: %v (%v)", effects, err)
+	}
+	if _, err := parseMasteryEffects([]byte(`["x"]`)); err == nil {
+		t.Fatal("failed to detect invalid mastery effects")
+	}
+}
@@ -0,0 +1,137 @@
+package poeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// GetCharactersOptions contains the request parameters for the character list
+// endpoint. All parameters are optional. Without an Account, the characters
+// returned belong to the account which granted the OAuth token, which requires
+// the ScopeAccountCharacters scope.
+type GetCharactersOptions struct {
+	// The name of another account whose characters to retrieve, such as
+	// "Player#1234". No OAuth token is needed, but the account's characters
+	// tab must be public, or ErrForbidden is returned. Only summary
+	// information is available for these characters.
+	Account string
+
+	// The realm of the characters to retrieve.
+	// Valid options: 'pc', 'xbox', or 'sony'.
+	Realm string
+}
+
+func validateGetCharactersOptions(opts GetCharactersOptions) error {
+	if opts.Realm != "" {
+		if _, ok := validRealms[opts.Realm]; !ok {
+			return ErrInvalidRealm
+		}
+	}
+	return nil
+}
+
+// charactersPath returns the character endpoint for the given realm. The pc
+// realm is the default, and is omitted from the path.
+func (c *client) charactersPath(realm string) string {
+	if realm == "" || realm == defaultRealm {
+		return c.formatURL(charactersEndpoint)
+	}
+	return fmt.Sprintf("%s/%s", c.formatURL(charactersEndpoint), realm)
+}
+
+func (c *client) GetCharacters(opts GetCharactersOptions) ([]Character, error) {
+	return c.GetCharactersContext(context.Background(), opts)
+}
+
+func (c *client) GetCharactersContext(ctx context.Context, opts GetCharactersOptions) ([]Character, error) {
+	if err := validateGetCharactersOptions(opts); err != nil {
+		return []Character{}, err
+	}
+	if opts.Account != "" {
+		return c.getPublicCharacters(ctx, opts)
+	}
+	resp, err := c.get(ctx, c.charactersPath(opts.Realm))
+	if err != nil {
+		return []Character{}, err
+	}
+	return parseCharactersResponse(resp)
+}
+
+type charactersResponse struct {
+	Characters []Character `json:"characters"`
+}
+
+func parseCharactersResponse(resp string) ([]Character, error) {
+	var r charactersResponse
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return []Character{}, err
+	}
+	if r.Characters == nil {
+		return []Character{}, nil
+	}
+	return r.Characters, nil
+}
+
+// getPublicCharacters retrieves the characters of another account from the
+// legacy character window endpoint.
+func (c *client) getPublicCharacters(ctx context.Context, opts GetCharactersOptions) ([]Character, error) {
+	realm := publicRealm(opts.Realm)
+	resp, err := c.get(ctx, c.characterWindowURL(publicCharactersEndpoint, opts.Account, "", realm))
+	if err != nil {
+		return []Character{}, err
+	}
+	return parsePublicCharactersResponse(resp, realm)
+}
+
+// publicRealm returns the realm to request from the character window
+// endpoints, which require it to be set.
+func publicRealm(realm string) string {
+	if realm == "" {
+		return defaultRealm
+	}
+	return realm
+}
+
+// characterWindowURL returns the URL of a character window endpoint for the
+// given account, and for a single character when a name is given.
+func (c *client) characterWindowURL(endpoint, account, character, realm string) string {
+	query := url.Values{}
+	query.Set("accountName", account)
+	if character != "" {
+		query.Set("character", character)
+	}
+	query.Set("realm", realm)
+	return c.formatTradeURL(endpoint) + "?" + query.Encode()
+}
+
+// publicCharacter is a character as listed by the character window endpoint.
+type publicCharacter struct {
+	Name       string `json:"name"`
+	League     string `json:"league"`
+	Class      string `json:"class"`
+	Level      int    `json:"level"`
+	Experience int    `json:"experience"`
+	LastActive bool   `json:"lastActive"`
+}
+
+func parsePublicCharactersResponse(resp, realm string) ([]Character, error) {
+	var r []publicCharacter
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return []Character{}, err
+	}
+	characters := make([]Character, 0, len(r))
+	for _, pc := range r {
+		characters = append(characters, Character{
+			Name:       pc.Name,
+			Level:      pc.Level,
+			Class:      pc.Class,
+			Experience: pc.Experience,
+			Realm:      realm,
+			League:     pc.League,
+			Current:    pc.LastActive,
+		})
+	}
+	return characters, nil
+}
@@ -0,0 +1,120 @@
+package poeapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGetCharacters(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	characters, err := c.GetCharacters(GetCharactersOptions{})
+	if err != nil {
+		t.Fatalf("failed to get characters: %v", err)
+	}
+	if len(characters) != 2 {
+		t.Fatalf("unexpected character count: expected 2, got %d",
+			len(characters))
+	}
+}
+
+func TestGetCharactersWithRealm(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	if _, err := c.GetCharacters(GetCharactersOptions{Realm: "xbox"}); err != nil {
+		t.Fatalf("failed to get characters for realm: %v", err)
+	}
+}
+
+func TestGetCharactersWithAccount(t *testing.T) {
+	c := client{
+		host:       testHost,
+		tradeHost:  testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	characters, err := c.GetCharacters(GetCharactersOptions{Account: "Player#1234"})
+	if err != nil {
+		t.Fatalf("failed to get characters for account: %v", err)
+	}
+	if len(characters) != 2 {
+		t.Fatalf("unexpected character count: expected 2, got %d",
+			len(characters))
+	}
+	if characters[0].Class != "Inquisitor" || !characters[0].Current ||
+		characters[0].Realm != "pc" || characters[1].League != "Hardcore" {
+		t.Fatalf("unexpected characters: %+v", characters)
+	}
+
+	_, err = c.GetCharacters(GetCharactersOptions{Account: privateAccount})
+	if !errors.Is(err, ErrForbidden) {
+		t.Fatalf("failed to detect private account: %v", err)
+	}
+}
+
+func TestGetCharactersWithInvalidOptions(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	_, err := c.GetCharacters(GetCharactersOptions{Realm: "testrealm"})
+	if err != ErrInvalidRealm {
+		t.Fatal("failed to detect invalid realm in characters request")
+	}
+}
+
+func TestParseCharactersResponse(t *testing.T) {
+	resp, err := loadFixture("fixtures/characters.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	characters, err := parseCharactersResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse characters response: %v", err)
+	}
+	if characters[0].League != "Standard" || !characters[0].Current {
+		t.Fatalf("unexpected character: %+v", characters[0])
+	}
+	if !characters[1].Deleted {
+		t.Fatal("failed to parse deleted character")
+	}
+}
+
+func TestParseCharactersResponseWithInvalidJSON(t *testing.T) {
+	resp, err := loadFixture("fixtures/invalid.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if _, err := parseCharactersResponse(resp); err == nil {
+		t.Fatal("failed to detect invalid json")
+	}
+}
+
+func TestParsePublicCharactersResponseWithInvalidJSON(t *testing.T) {
+	resp, err := loadFixture("fixtures/invalid.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if _, err := parsePublicCharactersResponse(resp, "pc"); err == nil {
+		t.Fatal("failed to detect invalid json")
+	}
+}
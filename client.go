@@ -1,10 +1,9 @@
 package poeapi
 
 import (
-	"fmt"
+	"context"
 	"net"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -18,29 +17,70 @@ const (
 	// overridden for testing purposes.
 	DefaultNinjaHost = "poe.ninja"
 
+	// DefaultTradeHost sets the hostname used for the trade search API, which
+	// is served by the main website rather than api.pathofexile.com.
+	DefaultTradeHost = "www.pathofexile.com"
+
 	// DefaultRateLimit sets the rate limit for all endpoints except for the
 	// stash endpoint. Most endpoints have a rate limit of 5 requests per
 	// second. Tests performed with the ratetest program (cmd/ratetest) show
 	// occasional failures at this rate, so we back down to 4 requests per
-	// second by default to err on the side of caution.
+	// second by default to err on the side of caution. This rate only applies
+	// until the API's X-Rate-Limit-* headers have been seen for an endpoint.
 	DefaultRateLimit = 4.0
 
 	// DefaultStashRateLimit sets the rate limit for the stash endpoint.
-	// The stash API has a rate limit of 1 request per second.
+	// The stash API has a rate limit of 1 request per second. Like
+	// DefaultRateLimit, this is replaced by the API's published limits once a
+	// response has been received.
 	DefaultStashRateLimit = 1.0
 
 	// DefaultCacheSize sets the number of items which can be stores in the
-	// in-memory LRU cache. A typical response from the API is around 500KB.
-	// By default, allow around 200x500KB=100MB total cache memory usage.
+	// in-memory LRU cache.
+	//
+	// Deprecated: Response sizes vary too widely for a count of responses to
+	// bound memory usage. DefaultClientOptions uses DefaultMaxCacheBytes
+	// instead.
 	DefaultCacheSize = 200
 
+	// DefaultMaxCacheBytes sets the total size of the responses which can be
+	// stored in the in-memory LRU cache. Responses range from a few hundred
+	// bytes for league rules to several hundred kilobytes for ladder pages.
+	DefaultMaxCacheBytes = 100 << 20
+
+	// DefaultMaxCacheResponseBytes sets the size of the largest single
+	// response which will be stored in the in-memory cache, so that one
+	// unusually large response cannot displace the rest of the cache.
+	DefaultMaxCacheResponseBytes = 10 << 20
+
+	// DefaultCacheTTL sets how long responses are cached when neither their
+	// headers nor DefaultCacheTTLs give a lifetime for them.
+	DefaultCacheTTL = 10 * time.Minute
+
 	// DefaultRequestTimeout sets the time to wait before canceling HTTP
 	// requests. Some endpoits take over 2-3s to respond, so we use 5s as a
 	// a default.
 	DefaultRequestTimeout = 5 * time.Second
 )
 
+// DefaultCacheTTLs sets how long responses from each endpoint are cached when
+// their headers do not give a lifetime. League and ladder data changes over
+// the course of a league, and trade listings change constantly, so these are
+// kept short.
+var DefaultCacheTTLs = map[string]time.Duration{
+	leaguesEndpoint:     10 * time.Minute,
+	leagueRulesEndpoint: time.Hour,
+	laddersEndpoint:     5 * time.Minute,
+	pvpMatchesEndpoint:  5 * time.Minute,
+	charactersEndpoint:  time.Minute,
+	"/api/trade":        time.Minute,
+}
+
 // APIClient provides methods for interacting with the Path of Exile API.
+//
+// Every request method has a Context variant which accepts a context.Context. When the
+// context is canceled or its deadline passes, any pending rate limit wait or
+// in-flight HTTP request is aborted and the context's error is returned.
 type APIClient interface {
 	// GetLadder sends multiple ladder requests to construct the entire ladder
 	// for a given league in a single call. Ladders contain information about
@@ -48,38 +88,133 @@ type APIClient interface {
 	// be returned.
 	GetLadder(GetLadderOptions) (Ladder, error)
 
+	// GetLadderContext is like GetLadder, but uses the provided context. If
+	// any page request fails, the remaining page requests are canceled.
+	GetLadderContext(context.Context, GetLadderOptions) (Ladder, error)
+
 	// GetLeague retrieves all league (Standard, Hardcore, etc.) from the API.
 	// Responses include information such as start and end times and rules for
 	// the league.
 	GetLeagues(GetLeaguesOptions) ([]League, error)
 
+	// GetLeaguesContext is like GetLeagues, but uses the provided context.
+	GetLeaguesContext(context.Context, GetLeaguesOptions) ([]League, error)
+
 	// GetLeague retrieves a single league from the API by ID.
 	GetLeague(GetLeagueOptions) (League, error)
 
+	// GetLeagueContext is like GetLeague, but uses the provided context.
+	GetLeagueContext(context.Context, GetLeagueOptions) (League, error)
+
 	// GetLeagueRules retrieves all available league modifiers from the API.
 	// These modifiers affect league mechanics, such as the 'Turbo' rule
 	// granting increased attack, cast, and movement speed to monsters.
 	GetLeagueRules() ([]LeagueRule, error)
 
+	// GetLeagueRulesContext is like GetLeagueRules, but uses the provided
+	// context.
+	GetLeagueRulesContext(context.Context) ([]LeagueRule, error)
+
 	// GetLeagueRule retrieves a single rule from the API by ID.
 	GetLeagueRule(GetLeagueRuleOptions) (LeagueRule, error)
 
+	// GetLeagueRuleContext is like GetLeagueRule, but uses the provided
+	// context.
+	GetLeagueRuleContext(context.Context, GetLeagueRuleOptions) (LeagueRule, error)
+
 	// GetPVPMatches retrieves past or upcoming PVP matches from the API.
 	// Specific seasons may be requested in order to view past events.
 	// Alternatively, not specifying a season returns all upcoming events.
 	GetPVPMatches(GetPVPMatchesOptions) ([]PVPMatch, error)
 
+	// GetPVPMatchesContext is like GetPVPMatches, but uses the provided
+	// context.
+	GetPVPMatchesContext(context.Context, GetPVPMatchesOptions) ([]PVPMatch, error)
+
+	// GetCharacters retrieves the characters of the account which granted the
+	// client's OAuth token, or of another account with a public profile when
+	// an Account is given. Only summary information is included; use
+	// GetCharacter for equipment, inventory, and passives.
+	GetCharacters(GetCharactersOptions) ([]Character, error)
+
+	// GetCharactersContext is like GetCharacters, but uses the provided
+	// context.
+	GetCharactersContext(context.Context, GetCharactersOptions) ([]Character, error)
+
+	// GetCharacter retrieves a single character by name, including its
+	// equipment, inventory, socketed jewels, and passive allocations. The
+	// characters of other accounts with public profiles are retrieved by
+	// setting an Account.
+	GetCharacter(GetCharacterOptions) (Character, error)
+
+	// GetCharacterContext is like GetCharacter, but uses the provided context.
+	GetCharacterContext(context.Context, GetCharacterOptions) (Character, error)
+
+	// GetPassives retrieves the passive skill allocations of a single
+	// character, including mastery effects, jewels, and bandit choice. The
+	// passivetree package can convert these into a passive tree URL.
+	GetPassives(GetCharacterOptions) (Passives, error)
+
+	// GetPassivesContext is like GetPassives, but uses the provided context.
+	GetPassivesContext(context.Context, GetCharacterOptions) (Passives, error)
+
 	// GetStashes retrieves a batch of stashes from the trade API. Each response
 	// contains a set of stashes which can be parsed for specific items.
 	// Responses also include a "next change ID" which is used to request the
 	// next set of stashes in chronological order (by publish time).
 	GetStashes(GetStashOptions) (StashResponse, error)
 
+	// GetStashesContext is like GetStashes, but uses the provided context.
+	GetStashesContext(context.Context, GetStashOptions) (StashResponse, error)
+
 	// GetLatestStashID retrieves the latest stash tab ID from poe.ninja. This
 	// is helpful when building real-time trade applications, as not specifying
 	// a stash ID starts from the beginning of time. This makes a single request
 	// to poe.ninja's API, and caches the response to avoid subsequent traffic.
 	GetLatestStashID() (string, error)
+
+	// GetLatestStashIDContext is like GetLatestStashID, but uses the provided
+	// context.
+	GetLatestStashIDContext(context.Context) (string, error)
+
+	// Search performs a trade search, as on the official trade site, and
+	// returns the IDs of the matching listings. Use Fetch to retrieve the
+	// listings themselves.
+	Search(TradeSearchOptions) (TradeSearchResult, error)
+
+	// SearchContext is like Search, but uses the provided context.
+	SearchContext(context.Context, TradeSearchOptions) (TradeSearchResult, error)
+
+	// Fetch retrieves trade listings by ID, including the item, price, and
+	// seller. The trade API returns at most 10 listings per request, so
+	// larger requests are split into batches which are sent in turn.
+	Fetch(TradeFetchOptions) ([]TradeListing, error)
+
+	// FetchContext is like Fetch, but uses the provided context. If any batch
+	// fails, the remaining batches are not requested.
+	FetchContext(context.Context, TradeFetchOptions) ([]TradeListing, error)
+
+	// Exchange queries the bulk exchange for sellers offering any of the
+	// wanted currencies in return for any of the currencies the caller has,
+	// identified by trade API IDs such as "chaos" and "divine". Offers are
+	// returned best ratio first; NewOrderBooks groups them by currency pair.
+	Exchange(want, have []string, league string) ([]ExchangeOffer, error)
+
+	// ExchangeContext is like Exchange, but uses the provided context.
+	ExchangeContext(ctx context.Context, want, have []string, league string) ([]ExchangeOffer, error)
+
+	// Invalidate removes cached responses for endpoints whose path begins
+	// with prefix, such as "/leagues" or "/ladders/Standard", so that they
+	// are requested again. An empty prefix clears the cache. It returns the
+	// number of responses removed.
+	Invalidate(prefix string) int
+
+	// CacheStats returns the number of cache hits, misses and evictions, the
+	// size of the cache, and the number of calls which were coalesced with
+	// identical concurrent calls. Only Coalesced is set when caching is
+	// disabled, or when a custom Cache does not implement
+	// CacheStatsReporter.
+	CacheStats() CacheStats
 }
 
 type client struct {
@@ -87,14 +222,23 @@ type client struct {
 
 	host      string
 	ninjaHost string
+	tradeHost string
 
 	useSSL      bool
 	useCache    bool
 	useDNSCache bool
 
+	oauth       *OAuthConfig
+	tokenSource TokenSource
+
 	limiter  *ratelimiter
-	cache    *responsecache
+	retry    RetryPolicy
+	cache    Cache
 	dnscache *dnscache
+	flights  *flightGroup
+
+	cacheTTL  time.Duration
+	cacheTTLs map[string]time.Duration
 }
 
 // NewAPIClient configures and returns an APIClient.
@@ -103,47 +247,92 @@ func NewAPIClient(opts ClientOptions) (APIClient, error) {
 		return nil, err
 	}
 
+	if opts.TradeHost == "" {
+		opts.TradeHost = DefaultTradeHost
+	}
+
 	c := &client{
 		host:        opts.Host,
 		ninjaHost:   opts.NinjaHost,
+		tradeHost:   opts.TradeHost,
 		useSSL:      opts.UseSSL,
 		useCache:    opts.UseCache,
 		useDNSCache: opts.UseDNSCache,
 		limiter:     newRateLimiter(opts.RateLimit, opts.StashRateLimit),
+		flights:     newFlightGroup(),
+		retry:       opts.Retry,
+		oauth:       opts.OAuth,
+		tokenSource: opts.TokenSource,
+		cacheTTL:    opts.CacheTTL,
+		cacheTTLs:   opts.CacheTTLs,
 	}
 
 	if opts.UseCache {
-		cache, err := newResponseCache(opts.CacheSize)
-		if err != nil {
-			return nil, err
+		c.cache = opts.Cache
+		if c.cache == nil {
+			cache, err := newResponseCache(MemoryCacheOptions{
+				MaxEntries:       opts.CacheSize,
+				MaxBytes:         opts.MaxCacheBytes,
+				MaxResponseBytes: opts.MaxCacheResponseBytes,
+			})
+			if err != nil {
+				return nil, err
+			}
+			c.cache = cache
 		}
-		c.cache = cache
 	}
 
-	if opts.UseDNSCache {
+	c.httpClient = c.newHTTPClient(opts)
+	return c, nil
+}
+
+// newHTTPClient builds the HTTP client used for all requests. A provided
+// HTTPClient or Transport is used as-is, otherwise a transport is created
+// which optionally resolves hosts through the DNS cache. In every case, the
+// configured middleware wraps the resulting transport.
+func (c *client) newHTTPClient(opts ClientOptions) *http.Client {
+	var httpClient http.Client
+	switch {
+	case opts.HTTPClient != nil:
+		// Copy the client so that wrapping its transport does not affect
+		// the caller's other uses of it.
+		httpClient = *opts.HTTPClient
+	case opts.Transport != nil:
+		httpClient = http.Client{
+			Transport: opts.Transport,
+			Timeout:   opts.RequestTimeout,
+		}
+	case opts.UseDNSCache:
 		c.dnscache = newDNSCache()
-		c.httpClient = &http.Client{
+		httpClient = http.Client{
 			Transport: &http.Transport{
 				// When a connection dials an address for the first time, if the
 				// host is DefaultHost, resolve the IP using the local DNS
 				// cache.
-				Dial: func(proto, addr string) (net.Conn, error) {
-					a := strings.Split(addr, ":")
-					ip, err := c.dnscache.Get(a[0])
+				DialContext: func(ctx context.Context, proto, addr string) (net.Conn, error) {
+					host, port, err := net.SplitHostPort(addr)
+					if err != nil {
+						return nil, err
+					}
+					ip, err := c.dnscache.Get(host)
 					if err != nil {
 						return nil, err
 					}
-					newAddr := fmt.Sprintf("%s:%s", ip, a[1])
-					return net.Dial(proto, newAddr)
+					var d net.Dialer
+					return d.DialContext(ctx, proto, net.JoinHostPort(ip, port))
 				},
 			},
 			Timeout: opts.RequestTimeout,
 		}
-	} else {
-		c.httpClient = &http.Client{Timeout: opts.RequestTimeout}
+	default:
+		httpClient = http.Client{Timeout: opts.RequestTimeout}
 	}
 
-	return c, nil
+	if len(opts.Middleware) > 0 {
+		httpClient.Transport = chainMiddleware(httpClient.Transport,
+			opts.Middleware)
+	}
+	return &httpClient
 }
 
 // ClientOptions contains settings for client initialization.
@@ -154,6 +343,10 @@ type ClientOptions struct {
 	// The hostname used for poe.ninja requests.
 	NinjaHost string
 
+	// The hostname used for trade search requests. Defaults to
+	// DefaultTradeHost when empty.
+	TradeHost string
+
 	// Set to false if your network does not allow outbound HTTPS traffic.
 	UseSSL bool
 
@@ -162,37 +355,95 @@ type ClientOptions struct {
 	// a cached stash means we will never get a new change ID.
 	UseCache bool
 
-	// The number of items which can be stored in the cache. Most endpoints
-	// have a response size up to 500KB or so.
+	// The number of items which can be stored in the cache. Zero means no
+	// limit, in which case MaxCacheBytes must be set. Ignored when Cache is
+	// set.
 	CacheSize int
 
+	// The total size of the responses which can be stored in the cache, in
+	// bytes. Zero means no limit, in which case CacheSize must be set.
+	// Ignored when Cache is set.
+	MaxCacheBytes int64
+
+	// The size of the largest single response which will be cached, in bytes.
+	// Zero means responses up to MaxCacheBytes are cached. Ignored when Cache
+	// is set.
+	MaxCacheResponseBytes int64
+
+	// An optional cache backend, such as a FileCache, used when UseCache is
+	// true. Defaults to an in-memory LRU cache bounded by CacheSize and
+	// MaxCacheBytes.
+	Cache Cache
+
+	// How long to cache responses which do not set Cache-Control or Expires
+	// headers. Zero means such responses are cached until evicted.
+	CacheTTL time.Duration
+
+	// Overrides CacheTTL for particular endpoints, keyed by path prefix such
+	// as "/leagues". The longest matching prefix is used.
+	CacheTTLs map[string]time.Duration
+
 	// Set to true to cache DNS resolution locally, speeding up subsequent
-	// requests. Go's resolver does not cache by default.
+	// requests. Go's resolver does not cache by default. Ignored when
+	// HTTPClient or Transport is set.
 	UseDNSCache bool
 
 	// The number of requests per second for all API endpoints except the stash
-	// tab endpoint. The API will ratelimit clients above 5rps.
+	// tab endpoint. The API will ratelimit clients above 5rps. Once a response
+	// includes X-Rate-Limit-* headers, the published limits for that endpoint
+	// are used instead, even when this is set to UnlimitedRate.
 	RateLimit float64
 
 	// The number of requests per second for the stash endpoint. The API
-	// will ratelimit clients above 1rps.
+	// will ratelimit clients above 1rps. Replaced by the published limits in
+	// the same way as RateLimit.
 	StashRateLimit float64
 
 	// Time to wait before canceling HTTP requests.
 	RequestTimeout time.Duration
+
+	// An optional HTTP client to send requests with, for example one which is
+	// configured with a proxy or client certificates. The client's own Timeout
+	// is used instead of RequestTimeout. Mutually exclusive with Transport.
+	HTTPClient *http.Client
+
+	// An optional transport to send requests with. RequestTimeout still
+	// applies. Mutually exclusive with HTTPClient.
+	Transport http.RoundTripper
+
+	// The registered OAuth client. When set, requests to the API send the
+	// User-Agent header required for OAuth clients.
+	OAuth *OAuthConfig
+
+	// Supplies the OAuth tokens sent with requests to the API. Requires OAuth
+	// to be set. Use OAuth.TokenSource to renew tokens automatically.
+	TokenSource TokenSource
+
+	// Middleware wraps the transport of every request, in order: the first
+	// middleware sees each request first and each response last.
+	Middleware []Middleware
+
+	// Controls retries of rate limited requests, server failures, and network
+	// timeouts. The zero value disables retries.
+	Retry RetryPolicy
 }
 
 // DefaultClientOptions initializes the client with the most common settings.
 var DefaultClientOptions = ClientOptions{
-	Host:           DefaultHost,
-	NinjaHost:      DefaultNinjaHost,
-	UseSSL:         true,
-	UseCache:       true,
-	CacheSize:      DefaultCacheSize,
-	UseDNSCache:    true,
-	RateLimit:      DefaultRateLimit,
-	StashRateLimit: DefaultStashRateLimit,
-	RequestTimeout: DefaultRequestTimeout,
+	Host:                  DefaultHost,
+	NinjaHost:             DefaultNinjaHost,
+	TradeHost:             DefaultTradeHost,
+	UseSSL:                true,
+	UseCache:              true,
+	MaxCacheBytes:         DefaultMaxCacheBytes,
+	MaxCacheResponseBytes: DefaultMaxCacheResponseBytes,
+	CacheTTL:              DefaultCacheTTL,
+	CacheTTLs:             DefaultCacheTTLs,
+	UseDNSCache:           true,
+	RateLimit:             DefaultRateLimit,
+	StashRateLimit:        DefaultStashRateLimit,
+	RequestTimeout:        DefaultRequestTimeout,
+	Retry:                 DefaultRetryPolicy,
 }
 
 func validateClientOptions(opts ClientOptions) error {
@@ -202,8 +453,21 @@ func validateClientOptions(opts ClientOptions) error {
 	if opts.NinjaHost == "" {
 		return ErrInvalidNinjaHost
 	}
-	if opts.UseCache && opts.CacheSize < 1 {
-		return ErrInvalidCacheSize
+	if opts.UseCache && opts.Cache == nil {
+		if opts.CacheSize < 0 || opts.MaxCacheBytes < 0 || opts.MaxCacheResponseBytes < 0 {
+			return ErrInvalidCacheSize
+		}
+		if opts.CacheSize == 0 && opts.MaxCacheBytes == 0 {
+			return ErrInvalidCacheSize
+		}
+	}
+	if opts.CacheTTL < 0 {
+		return ErrInvalidCacheTTL
+	}
+	for _, ttl := range opts.CacheTTLs {
+		if ttl < 0 {
+			return ErrInvalidCacheTTL
+		}
 	}
 	if opts.RateLimit < 0 {
 		return ErrInvalidRateLimit
@@ -214,5 +478,18 @@ func validateClientOptions(opts ClientOptions) error {
 	if opts.RequestTimeout < 1*time.Millisecond {
 		return ErrInvalidRequestTimeout
 	}
+	if opts.HTTPClient != nil && opts.Transport != nil {
+		return ErrInvalidHTTPClient
+	}
+	if opts.OAuth != nil {
+		if err := validateOAuthConfig(opts.OAuth); err != nil {
+			return err
+		}
+	} else if opts.TokenSource != nil {
+		return ErrInvalidOAuthConfig
+	}
+	if err := validateRetryPolicy(opts.Retry); err != nil {
+		return err
+	}
 	return nil
 }
@@ -72,6 +72,26 @@ func TestValidateOptionsInvalidCacheSize(t *testing.T) {
 	}
 }
 
+func TestValidateOptionsByteBoundedCache(t *testing.T) {
+	opts := DefaultClientOptions
+	opts.CacheSize = 0
+	if err := validateClientOptions(opts); err != nil {
+		t.Fatalf("failed to validate byte-bounded cache: %v", err)
+	}
+	opts.MaxCacheBytes = -1
+	if err := validateClientOptions(opts); err != ErrInvalidCacheSize {
+		t.Fatal("failed to detect invalid cache byte limit")
+	}
+}
+
+func TestValidateOptionsInvalidCacheTTL(t *testing.T) {
+	opts := DefaultClientOptions
+	opts.CacheTTLs = map[string]time.Duration{leaguesEndpoint: -time.Second}
+	if err := validateClientOptions(opts); err != ErrInvalidCacheTTL {
+		t.Fatal("failed to detect invalid cache ttl")
+	}
+}
+
 func TestValidateOptionsInvalidRateLimit(t *testing.T) {
 	opts := ClientOptions{
 		Host:           DefaultHost,
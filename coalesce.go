@@ -0,0 +1,88 @@
+package poeapi
+
+import (
+	"context"
+	"sync"
+)
+
+// flightGroup coalesces concurrent identical requests, so that callers which
+// ask for a URL while a request for it is already in flight wait for and
+// share its result, including any error, instead of sending their own.
+type flightGroup struct {
+	flights   map[string]*flight
+	coalesced int64
+	lock      sync.Mutex
+}
+
+// flight is a request which is in flight.
+type flight struct {
+	done chan struct{}
+	resp apiResponse
+	err  error
+
+	// Set when the request was aborted by the context of the caller which
+	// sent it, in which case waiting callers send the request again.
+	canceled bool
+}
+
+func newFlightGroup() *flightGroup {
+	return &flightGroup{flights: make(map[string]*flight)}
+}
+
+// do calls fn for the given key, unless a call for the key is already in
+// flight, in which case it waits for that call's result. Waiting is aborted
+// when the context is canceled.
+func (g *flightGroup) do(ctx context.Context, key string, fn func(context.Context) (apiResponse, error)) (apiResponse, error) {
+	for {
+		g.lock.Lock()
+		f, ok := g.flights[key]
+		if !ok {
+			f = &flight{done: make(chan struct{})}
+			g.flights[key] = f
+			g.lock.Unlock()
+
+			f.resp, f.err = fn(ctx)
+			f.canceled = f.err != nil && ctx.Err() != nil
+
+			g.lock.Lock()
+			delete(g.flights, key)
+			g.lock.Unlock()
+			close(f.done)
+			return f.resp, f.err
+		}
+		g.lock.Unlock()
+
+		select {
+		case <-f.done:
+		case <-ctx.Done():
+			return apiResponse{}, ctx.Err()
+		}
+		// Another caller's cancellation says nothing about this request, so
+		// send it again.
+		if f.canceled {
+			continue
+		}
+
+		g.lock.Lock()
+		g.coalesced++
+		g.lock.Unlock()
+		return f.resp, f.err
+	}
+}
+
+// Coalesced returns the number of calls which shared the result of another
+// call rather than sending their own request.
+func (g *flightGroup) Coalesced() int64 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.coalesced
+}
+
+// withCoalescing wraps fn so that concurrent calls with the same key share a
+// single call. Clients created without a flight group do not coalesce calls.
+func (c *client) withCoalescing(ctx context.Context, key string, fn func(context.Context) (apiResponse, error)) (apiResponse, error) {
+	if c.flights == nil {
+		return fn(ctx)
+	}
+	return c.flights.do(ctx, key, fn)
+}
@@ -0,0 +1,149 @@
+package poeapi
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// coalesceDelay gives concurrent callers time to join an in-flight request.
+const coalesceDelay = 50 * time.Millisecond
+
+func TestFlightGroup(t *testing.T) {
+	var (
+		g     = newFlightGroup()
+		calls int32
+		wg    sync.WaitGroup
+	)
+	fn := func(ctx context.Context) (apiResponse, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(coalesceDelay)
+		return apiResponse{body: "shared"}, ErrServerFailure
+	}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp, err := g.do(context.Background(), "key", fn)
+			if resp.body != "shared" || err != ErrServerFailure {
+				t.Errorf("unexpected shared result: %q, %v", resp.body, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Fatalf("unexpected call count: expected 1, got %d", calls)
+	}
+	if n := g.Coalesced(); n != 9 {
+		t.Fatalf("unexpected coalesced count: expected 9, got %d", n)
+	}
+
+	// Later calls are not coalesced with finished ones.
+	g.do(context.Background(), "key", fn)
+	if calls != 2 {
+		t.Fatalf("coalesced call with finished call: %d calls", calls)
+	}
+}
+
+func TestFlightGroupCanceledWaiter(t *testing.T) {
+	var (
+		g       = newFlightGroup()
+		release = make(chan struct{})
+		done    = make(chan struct{})
+	)
+	go func() {
+		g.do(context.Background(), "key", func(ctx context.Context) (apiResponse, error) {
+			<-release
+			return apiResponse{}, nil
+		})
+		close(done)
+	}()
+	time.Sleep(coalesceDelay)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := g.do(ctx, "key", nil); err != context.Canceled {
+		t.Fatalf("failed to abort wait for canceled context: %v", err)
+	}
+	close(release)
+	<-done
+}
+
+func TestFlightGroupCanceledLeader(t *testing.T) {
+	var (
+		g           = newFlightGroup()
+		ctx, cancel = context.WithCancel(context.Background())
+		calls       int32
+		done        = make(chan struct{})
+	)
+	fn := func(ctx context.Context) (apiResponse, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return apiResponse{}, ctx.Err()
+		}
+		return apiResponse{body: "ok"}, nil
+	}
+	go func() {
+		if _, err := g.do(ctx, "key", fn); err != context.Canceled {
+			t.Errorf("unexpected leader error: %v", err)
+		}
+		close(done)
+	}()
+	time.Sleep(coalesceDelay)
+
+	go func() {
+		time.Sleep(coalesceDelay)
+		cancel()
+	}()
+	resp, err := g.do(context.Background(), "key", fn)
+	if err != nil || resp.body != "ok" {
+		t.Fatalf("failed to resend request after leader was canceled: %q, %v",
+			resp.body, err)
+	}
+	<-done
+	if calls != 2 {
+		t.Fatalf("unexpected call count: expected 2, got %d", calls)
+	}
+}
+
+func TestGetCoalescesConcurrentRequests(t *testing.T) {
+	var requests int32
+	c := client{
+		host:    testHost,
+		limiter: newRateLimiter(UnlimitedRate, UnlimitedRate),
+		flights: newFlightGroup(),
+		httpClient: &http.Client{
+			Timeout: testTimeout,
+			Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				atomic.AddInt32(&requests, 1)
+				time.Sleep(coalesceDelay)
+				return http.DefaultTransport.RoundTrip(req)
+			}),
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			league, err := c.GetLeague(GetLeagueOptions{ID: "Standard"})
+			if err != nil || league.Name != "Standard" {
+				t.Errorf("failed to get league: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if requests != 1 {
+		t.Fatalf("unexpected request count: expected 1, got %d", requests)
+	}
+	if n := c.CacheStats().Coalesced; n != 9 {
+		t.Fatalf("unexpected coalesced count: expected 9, got %d", n)
+	}
+}
@@ -9,13 +9,18 @@ Usage:
 	clientOpts := poeapi.ClientOptions{
 	    Host:              "api.pathofexile.com", // The primary API domain.
 	    NinjaHost:         "poe.ninja",           // Used to get latest stash ID.
+	    TradeHost:         "www.pathofexile.com", // Used for trade searches.
 	    UseSSL:            true,                  // Use HTTPS for requests.
 	    UseCache:          true,                  // Enable the in-memory cache.
 	    UseDNSCache:       true,                  // Enable the in-memory DNS resolution cache.
-	    CacheSize:         200,                   // Number of items to store.
+	    MaxCacheBytes:     100 << 20,             // Total size of cached responses.
+	    MaxCacheResponseBytes: 10 << 20,         // Largest single response to cache.
+	    CacheTTL:          10 * time.Minute,      // Lifetime of responses without cache headers.
+	    CacheTTLs:         poeapi.DefaultCacheTTLs, // Per-endpoint overrides of CacheTTL.
 	    RateLimit:         4.0,                   // Requests per second.
 	    StashRateLimit:    1.0,                   // Requests per second for trade API.
-	    RequestTimeout:    5 * time.Second        // Time to wait before canceling requests.
+	    RequestTimeout:    5 * time.Second,       // Time to wait before canceling requests.
+	    Retry:             poeapi.DefaultRetryPolicy, // Retry transient failures with backoff.
 	} // This is equivalent to poeapi.DefaultClientOptions.
 
 	client, err := poeapi.NewAPIClient(clientOpts)
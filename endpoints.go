@@ -3,14 +3,25 @@ package poeapi
 import "fmt"
 
 const (
-	leaguesEndpoint     = "/leagues"
-	leagueRulesEndpoint = "/league-rules"
-	laddersEndpoint     = "/ladders"
-	pvpMatchesEndpoint  = "/pvp-matches"
-	stashTabsEndpoint   = "/public-stash-tabs"
+	leaguesEndpoint       = "/leagues"
+	leagueRulesEndpoint   = "/league-rules"
+	laddersEndpoint       = "/ladders"
+	pvpMatchesEndpoint    = "/pvp-matches"
+	stashTabsEndpoint     = "/public-stash-tabs"
+	charactersEndpoint    = "/character"
+	tradeSearchEndpoint   = "/api/trade/search"
+	tradeFetchEndpoint    = "/api/trade/fetch"
+	tradeExchangeEndpoint = "/api/trade/exchange"
 
 	latestChangeURL = "/api/Data/GetStats"
 
+	// The legacy character window endpoints serve the characters of any
+	// account whose profile is public. They are served by the trade host,
+	// without OAuth.
+	publicCharactersEndpoint = "/character-window/get-characters"
+	publicItemsEndpoint      = "/character-window/get-items"
+	publicPassivesEndpoint   = "/character-window/get-passive-skills"
+
 	httpProtocol  = "http"
 	httpsProtocol = "https"
 )
@@ -24,3 +35,11 @@ func (c *client) formatURL(endpoint string) string {
 	}
 	return fmt.Sprintf("%s://%s%s", httpProtocol, c.host, endpoint)
 }
+
+// formatTradeURL is like formatURL, but uses the trade host.
+func (c *client) formatTradeURL(endpoint string) string {
+	if c.useSSL {
+		return fmt.Sprintf("%s://%s%s", httpsProtocol, c.tradeHost, endpoint)
+	}
+	return fmt.Sprintf("%s://%s%s", httpProtocol, c.tradeHost, endpoint)
+}
@@ -29,3 +29,18 @@ func TestFormatHTTPURL(t *testing.T) {
 			expected, client.formatURL(laddersEndpoint))
 	}
 }
+
+func TestFormatTradeURL(t *testing.T) {
+	var (
+		expected = "https://www.pathofexile.com/api/trade/search"
+		client   = client{
+			host:      "api.pathofexile.com",
+			tradeHost: "www.pathofexile.com",
+			useSSL:    true,
+		}
+	)
+	if client.formatTradeURL(tradeSearchEndpoint) != expected {
+		t.Fatalf("failed to format url: expected %s, got %s",
+			expected, client.formatTradeURL(tradeSearchEndpoint))
+	}
+}
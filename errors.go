@@ -1,6 +1,13 @@
 package poeapi
 
-import "errors"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
 
 var (
 	// ErrBadRequest is raised when we have sent a malformed request to the API.
@@ -9,6 +16,10 @@ var (
 	// ErrNotFound is raised when we have requested an invalid URL.
 	ErrNotFound = errors.New("url not found")
 
+	// ErrForbidden is raised when the API denies access to a resource, such as
+	// the characters of an account whose profile is private.
+	ErrForbidden = errors.New("forbidden")
+
 	// ErrRateLimited is raised when we exceed the API rate limits.
 	ErrRateLimited = errors.New("rate limited")
 
@@ -37,9 +48,42 @@ var (
 	// ErrInvalidRequestTimeout is raised when request timeout is too small.
 	ErrInvalidRequestTimeout = errors.New("invalid request timeout")
 
+	// ErrInvalidRetryPolicy is raised when the retry policy has negative
+	// values, a maximum backoff below the base backoff, or jitter outside of
+	// the range [0, 1].
+	ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+	// ErrInvalidHTTPClient is raised when both an HTTP client and a transport
+	// are provided in the client options.
+	ErrInvalidHTTPClient = errors.New("http client and transport are mutually exclusive")
+
+	// ErrInvalidOAuthConfig is raised when an OAuth client ID, version, or
+	// contact is missing, when a token source is provided without an OAuth
+	// configuration, or when a public client attempts a confidential flow.
+	ErrInvalidOAuthConfig = errors.New("invalid oauth config")
+
+	// ErrInvalidToken is raised when an OAuth token is missing or has expired
+	// and cannot be renewed.
+	ErrInvalidToken = errors.New("invalid oauth token")
+
+	// ErrMissingScope is raised when the OAuth token was not granted the scope
+	// required by the requested endpoint.
+	ErrMissingScope = errors.New("oauth token missing required scope")
+
 	// ErrInvalidCacheSize is raised when the cache size is out of range.
 	ErrInvalidCacheSize = errors.New("invalid cache size")
 
+	// ErrCacheEntryTooLarge is raised when a response is too large to be
+	// stored in the cache.
+	ErrCacheEntryTooLarge = errors.New("cache entry too large")
+
+	// ErrInvalidCacheTTL is raised when a cache TTL is negative.
+	ErrInvalidCacheTTL = errors.New("invalid cache ttl")
+
+	// ErrInvalidFileCache is raised when a file cache has no directory or a
+	// negative maximum size.
+	ErrInvalidFileCache = errors.New("invalid file cache options")
+
 	// ErrNotFoundInCache is raised when a value is requested from the cache
 	// before it is written.
 	ErrNotFoundInCache = errors.New("not found in cache")
@@ -91,7 +135,147 @@ var (
 	// request.
 	ErrInvalidLeagueID = errors.New("invalid league id")
 
+	// ErrInvalidCharacterName is raised when the character name is omitted
+	// from a character request.
+	ErrInvalidCharacterName = errors.New("invalid character name")
+
+	// ErrInvalidStashRiver is raised when a stash river is created without a
+	// client or with negative intervals.
+	ErrInvalidStashRiver = errors.New("invalid stash river options")
+
+	// ErrStashRiverStarted is raised when Run is called more than once on the
+	// same stash river.
+	ErrStashRiverStarted = errors.New("stash river already started")
+
+	// ErrInvalidStashTracker is raised when creating a StashTracker with a
+	// negative stash limit.
+	ErrInvalidStashTracker = errors.New("invalid stash tracker options")
+
+	// ErrNoPrice is raised when a note or stash tab name is not a price.
+	ErrNoPrice = errors.New("no price")
+
+	// ErrInvalidPrice is raised when a price note has a malformed amount or
+	// an unknown currency.
+	ErrInvalidPrice = errors.New("invalid price")
+
+	// ErrUnknownExchangeRate is raised when converting a price in a currency
+	// which is missing from the exchange rates.
+	ErrUnknownExchangeRate = errors.New("unknown exchange rate")
+
+	// ErrInvalidSockets is raised when a socket string such as "R-G-B B" is
+	// malformed.
+	ErrInvalidSockets = errors.New("invalid sockets")
+
+	// ErrInvalidItemText is raised when item text copied from the game is
+	// missing its rarity header.
+	ErrInvalidItemText = errors.New("invalid item text")
+
+	// ErrInvalidTradeSearch is raised when a trade search is missing its
+	// league or query.
+	ErrInvalidTradeSearch = errors.New("invalid trade search")
+
+	// ErrInvalidTradeFetch is raised when a trade fetch is missing its search
+	// ID or listing IDs.
+	ErrInvalidTradeFetch = errors.New("invalid trade fetch")
+
+	// ErrInvalidExchange is raised when a bulk exchange request is missing
+	// its league or its wanted or offered currencies.
+	ErrInvalidExchange = errors.New("invalid exchange request")
+
 	// ErrInvalidStashID is raised when the stash ID is omitted from a stash
 	// request.
 	ErrInvalidStashID = errors.New("invalid stash id")
 )
+
+// APIError is returned when the API responds with a non-200 status code. It
+// preserves the details of the failed response, and matches the sentinel error
+// for its status code when used with errors.Is, so callers may continue to
+// check for ErrRateLimited, ErrNotFound, and so on.
+type APIError struct {
+	// The HTTP status code of the response.
+	StatusCode int
+
+	// The path of the requested URL, such as "/ladders/Standard".
+	Endpoint string
+
+	// The response headers, including any X-Rate-Limit-* headers.
+	Header http.Header
+
+	// The delay requested by the server's Retry-After header, or zero when the
+	// header is absent.
+	RetryAfter time.Duration
+
+	// The error code and message from the API's JSON error body, when present.
+	Code    int
+	Message string
+
+	// The raw response body.
+	Body string
+
+	sentinel error
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("%v: status %d from %s: %s", e.sentinel,
+			e.StatusCode, e.Endpoint, e.Message)
+	}
+	return fmt.Sprintf("%v: status %d from %s", e.sentinel, e.StatusCode,
+		e.Endpoint)
+}
+
+// Unwrap returns the sentinel error for the response's status code.
+func (e *APIError) Unwrap() error {
+	return e.sentinel
+}
+
+// apiErrorBody is the JSON error format used by the API, e.g.:
+// {"error":{"code":3,"message":"Rate limit exceeded"}}
+type apiErrorBody struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// newAPIError builds an APIError from a failed response and its body. The
+// body is decoded on a best-effort basis, since not every failure (such as
+// those from proxies) uses the API's error format.
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	e := &APIError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		Body:       string(body),
+		sentinel:   parseError(resp.StatusCode),
+	}
+	if resp.Request != nil && resp.Request.URL != nil {
+		e.Endpoint = resp.Request.URL.Path
+	}
+
+	var b apiErrorBody
+	if err := json.Unmarshal(body, &b); err == nil {
+		e.Code = b.Error.Code
+		e.Message = b.Error.Message
+	}
+	return e
+}
+
+// parseRetryAfter converts a Retry-After header value into a duration. The
+// header may contain either a number of seconds or an HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,83 @@
+package poeapi
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAPIError(t *testing.T) {
+	c := client{
+		host:       testHost,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	_, err := c.getJSON(context.Background(), c.formatURL(rateLimitEndpoint))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if !errors.Is(err, ErrRateLimited) {
+		t.Fatal("failed to match APIError against ErrRateLimited")
+	}
+	if apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("unexpected status code: %d", apiErr.StatusCode)
+	}
+	if apiErr.Endpoint != rateLimitEndpoint {
+		t.Fatalf("unexpected endpoint: %s", apiErr.Endpoint)
+	}
+	if apiErr.RetryAfter != 2*time.Second {
+		t.Fatalf("unexpected retry after: %v", apiErr.RetryAfter)
+	}
+	if apiErr.Code != 3 || apiErr.Message != "Rate limit exceeded" {
+		t.Fatalf("failed to decode error body: %d %s", apiErr.Code,
+			apiErr.Message)
+	}
+}
+
+func TestAPIErrorWithoutBody(t *testing.T) {
+	c := client{
+		host:       testHost,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	_, err := c.getJSON(context.Background(), c.formatURL("/missing"))
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *APIError, got %T", err)
+	}
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatal("failed to match APIError against ErrNotFound")
+	}
+	if apiErr.Message != "" || apiErr.RetryAfter != 0 {
+		t.Fatal("unexpected details in APIError without body")
+	}
+}
+
+func TestParseCodeServiceUnavailable(t *testing.T) {
+	if err := parseError(http.StatusServiceUnavailable); err != ErrServerFailure {
+		t.Fatal("failed to detect server failure")
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	cases := map[string]time.Duration{
+		"":                              0,
+		"5":                             5 * time.Second,
+		"-1":                            0,
+		"invalid":                       0,
+		"Wed, 01 Jan 2020 00:00:30 GMT": 30 * time.Second,
+		"Tue, 31 Dec 2019 23:59:00 GMT": 0,
+	}
+	for value, expected := range cases {
+		if d := parseRetryAfter(value, now); d != expected {
+			t.Fatalf("failed to parse retry after %q: expected %v, got %v",
+				value, expected, d)
+		}
+	}
+}
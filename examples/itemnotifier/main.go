@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
 	"log"
+	"os"
+	"os/signal"
 
 	"github.com/willroberts/poeapi"
 )
@@ -9,6 +12,10 @@ import (
 var (
 	targetItem   = "Kaom's Heart"
 	targetLeague = "Standard"
+
+	// The last processed change ID is saved here, so that restarting the
+	// program resumes where it left off.
+	checkpointFile = "itemnotifier.checkpoint"
 )
 
 func main() {
@@ -17,25 +24,23 @@ func main() {
 		log.Fatal(err)
 	}
 
-	latest, err := client.GetLatestStashID()
+	river, err := poeapi.NewStashRiver(client, poeapi.StashRiverOptions{
+		Checkpointer: poeapi.NewFileCheckpointer(checkpointFile),
+	})
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	log.Printf("Searching for %s in %s league. Press Ctrl-C to exit.",
-		targetItem, targetLeague)
-	for {
-		stashes, err := client.GetStashes(poeapi.GetStashOptions{ID: latest})
-		if err != nil {
-			log.Fatal(err)
-		}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
 
-		if stashes.NextChangeID == latest {
-			continue
-		}
-		latest = stashes.NextChangeID
+	errCh := make(chan error, 1)
+	go func() { errCh <- river.Run(ctx) }()
 
-		for _, s := range stashes.Stashes {
+	log.Printf("Searching for %s in %s league. Press Ctrl-C to exit.",
+		targetItem, targetLeague)
+	for batch := range river.Batches() {
+		for _, s := range batch.Stashes {
 			for _, i := range s.Items {
 				if i.Name == targetItem && i.League == targetLeague {
 					if i.Note != "" {
@@ -48,5 +53,12 @@ func main() {
 				}
 			}
 		}
+		if err := river.Commit(batch); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if err := <-errCh; err != nil && err != context.Canceled {
+		log.Fatal(err)
 	}
 }
@@ -0,0 +1,268 @@
+package poeapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// exchangeRequest is the body of a bulk exchange request.
+type exchangeRequest struct {
+	Query struct {
+		Status tradeStatus `json:"status"`
+		Have   []string    `json:"have"`
+		Want   []string    `json:"want"`
+	} `json:"query"`
+	Sort   map[string]string `json:"sort"`
+	Engine string            `json:"engine"`
+}
+
+func newExchangeRequest(want, have []string) exchangeRequest {
+	var r exchangeRequest
+	r.Query.Status = tradeStatus{Option: TradeOnline}
+	r.Query.Have = have
+	r.Query.Want = want
+	r.Sort = map[string]string{"have": "asc"}
+	r.Engine = "new"
+	return r
+}
+
+func validateExchangeOptions(want, have []string, league string) error {
+	if len(want) == 0 || len(have) == 0 || league == "" {
+		return ErrInvalidExchange
+	}
+	return nil
+}
+
+// ExchangeAmount is a quantity of one currency in an exchange offer.
+type ExchangeAmount struct {
+	// The trade API's ID for the currency, such as "chaos" or "divine".
+	Currency string  `json:"currency"`
+	Amount   float64 `json:"amount"`
+}
+
+// ExchangeOffer is a seller's offer to trade one currency for another in
+// bulk. Pay is what the seller asks for, from the currencies the buyer has,
+// and Get is what the seller gives in return, from the currencies the buyer
+// wants. Offers may be taken any number of times, up to the seller's stock.
+type ExchangeOffer struct {
+	// The ID of the listing which holds the offer.
+	ID string
+
+	Account TradeAccount
+	Indexed time.Time
+	Whisper string
+
+	Pay ExchangeAmount
+	Get ExchangeAmount
+
+	// The number of units of the Get currency which the seller holds.
+	Stock int
+}
+
+// Ratio returns the number of units of the Get currency received for each
+// unit of the Pay currency. Higher ratios are better for the buyer.
+func (o ExchangeOffer) Ratio() float64 {
+	if o.Pay.Amount == 0 {
+		return 0
+	}
+	return o.Get.Amount / o.Pay.Amount
+}
+
+func (c *client) Exchange(want, have []string, league string) ([]ExchangeOffer, error) {
+	return c.ExchangeContext(context.Background(), want, have, league)
+}
+
+func (c *client) ExchangeContext(ctx context.Context, want, have []string, league string) ([]ExchangeOffer, error) {
+	if err := validateExchangeOptions(want, have, league); err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(newExchangeRequest(want, have))
+	if err != nil {
+		return nil, err
+	}
+
+	url := c.formatTradeURL(tradeExchangeEndpoint + "/" + url.PathEscape(league))
+	resp, err := c.post(ctx, url, body)
+	if err != nil {
+		return nil, err
+	}
+	return parseExchangeResponse(resp)
+}
+
+// exchangeResponse is the bulk exchange response, in which results are keyed
+// by listing ID, and each listing may hold several offers.
+type exchangeResponse struct {
+	ID     string                      `json:"id"`
+	Result map[string]*exchangeListing `json:"result"`
+	Total  int                         `json:"total"`
+}
+
+type exchangeListing struct {
+	ID      string `json:"id"`
+	Listing struct {
+		Indexed time.Time    `json:"indexed"`
+		Account TradeAccount `json:"account"`
+		Whisper string       `json:"whisper"`
+		Offers  []struct {
+			Exchange ExchangeAmount `json:"exchange"`
+			Item     struct {
+				ExchangeAmount
+				Stock int `json:"stock"`
+			} `json:"item"`
+		} `json:"offers"`
+	} `json:"listing"`
+}
+
+// parseExchangeResponse flattens the listings of an exchange response into
+// offers, ordered by descending ratio. Null listings are omitted.
+func parseExchangeResponse(resp string) ([]ExchangeOffer, error) {
+	var r exchangeResponse
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return nil, err
+	}
+
+	offers := make([]ExchangeOffer, 0, len(r.Result))
+	for id, l := range r.Result {
+		if l == nil {
+			continue
+		}
+		if l.ID != "" {
+			id = l.ID
+		}
+		for _, o := range l.Listing.Offers {
+			offers = append(offers, ExchangeOffer{
+				ID:      id,
+				Account: l.Listing.Account,
+				Indexed: l.Listing.Indexed,
+				Whisper: l.Listing.Whisper,
+				Pay:     o.Exchange,
+				Get:     o.Item.ExchangeAmount,
+				Stock:   o.Item.Stock,
+			})
+		}
+	}
+	sortExchangeOffers(offers)
+	return offers, nil
+}
+
+// sortExchangeOffers orders offers by descending ratio, breaking ties by
+// descending stock and then by ID, so that the order is deterministic.
+func sortExchangeOffers(offers []ExchangeOffer) {
+	sort.SliceStable(offers, func(i, j int) bool {
+		a, b := offers[i], offers[j]
+		if a.Ratio() != b.Ratio() {
+			return a.Ratio() > b.Ratio()
+		}
+		if a.Stock != b.Stock {
+			return a.Stock > b.Stock
+		}
+		return a.ID < b.ID
+	})
+}
+
+// OrderBook holds the offers for one pair of currencies, grouped into levels
+// by ratio with the best ratio first.
+type OrderBook struct {
+	// The currency the buyer pays.
+	Pay string
+
+	// The currency the buyer receives.
+	Get string
+
+	Levels []OrderBookLevel
+}
+
+// OrderBookLevel is the set of offers at a single ratio.
+type OrderBookLevel struct {
+	Ratio float64
+
+	// The combined stock of the level's offers.
+	Stock int
+
+	Offers []ExchangeOffer
+}
+
+// NewOrderBooks groups offers by currency pair and builds an order book for
+// each pair. Offers without stock are ignored. Books are ordered by the pay
+// currency and then the received currency.
+func NewOrderBooks(offers []ExchangeOffer) []OrderBook {
+	type pair struct{ pay, get string }
+	grouped := make(map[pair][]ExchangeOffer)
+	var pairs []pair
+	for _, o := range offers {
+		if o.Stock <= 0 || o.Pay.Amount <= 0 || o.Get.Amount <= 0 {
+			continue
+		}
+		p := pair{o.Pay.Currency, o.Get.Currency}
+		if _, ok := grouped[p]; !ok {
+			pairs = append(pairs, p)
+		}
+		grouped[p] = append(grouped[p], o)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].pay != pairs[j].pay {
+			return pairs[i].pay < pairs[j].pay
+		}
+		return pairs[i].get < pairs[j].get
+	})
+
+	books := make([]OrderBook, 0, len(pairs))
+	for _, p := range pairs {
+		group := grouped[p]
+		sortExchangeOffers(group)
+
+		book := OrderBook{Pay: p.pay, Get: p.get}
+		for _, o := range group {
+			n := len(book.Levels)
+			if n == 0 || book.Levels[n-1].Ratio != o.Ratio() {
+				book.Levels = append(book.Levels, OrderBookLevel{Ratio: o.Ratio()})
+				n++
+			}
+			book.Levels[n-1].Stock += o.Stock
+			book.Levels[n-1].Offers = append(book.Levels[n-1].Offers, o)
+		}
+		books = append(books, book)
+	}
+	return books
+}
+
+// Best returns the level with the best ratio, or false if the book is empty.
+func (b OrderBook) Best() (OrderBookLevel, bool) {
+	if len(b.Levels) == 0 {
+		return OrderBookLevel{}, false
+	}
+	return b.Levels[0], true
+}
+
+// Stock returns the combined stock of every level in the book.
+func (b OrderBook) Stock() int {
+	total := 0
+	for _, l := range b.Levels {
+		total += l.Stock
+	}
+	return total
+}
+
+// Cost estimates the amount of the Pay currency needed to receive the given
+// quantity of the Get currency, taking the best levels first. Offers are
+// treated as divisible, so the result may be lower than the cost of whole
+// trades. False is returned if the book's stock is insufficient.
+func (b OrderBook) Cost(quantity int) (float64, bool) {
+	cost := 0.0
+	remaining := quantity
+	for _, l := range b.Levels {
+		if remaining <= 0 {
+			break
+		}
+		n := l.Stock
+		if n > remaining {
+			n = remaining
+		}
+		cost += float64(n) / l.Ratio
+		remaining -= n
+	}
+	return cost, remaining <= 0
+}
@@ -0,0 +1,127 @@
+package poeapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExchangeRequestMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(newExchangeRequest([]string{"divine"}, []string{"chaos"}))
+	if err != nil {
+		t.Fatalf("failed to marshal exchange request: %v", err)
+	}
+	expected := `{"query":{"status":{"option":"online"},"have":["chaos"],` +
+		`"want":["divine"]},"sort":{"have":"asc"},"engine":"new"}`
+	if string(b) != expected {
+		t.Fatalf("unexpected exchange request: expected %s, got %s", expected, b)
+	}
+}
+
+func TestExchange(t *testing.T) {
+	c := newTestTradeClient()
+	offers, err := c.Exchange([]string{"divine", "exalted"}, []string{"chaos"}, "Standard")
+	if err != nil {
+		t.Fatalf("failed to query exchange: %v", err)
+	}
+	if len(offers) != 4 {
+		t.Fatalf("unexpected offer count: expected 4, got %d", len(offers))
+	}
+
+	expected := []struct {
+		id    string
+		get   string
+		stock int
+	}{
+		{"c3", "exalted", 50},
+		{"b2", "divine", 3},
+		{"a1", "divine", 10},
+		{"c3", "divine", 4},
+	}
+	for n, e := range expected {
+		o := offers[n]
+		if o.ID != e.id || o.Get.Currency != e.get || o.Stock != e.stock {
+			t.Fatalf("unexpected offer at %d: %+v", n, o)
+		}
+	}
+	if offers[1].Pay.Currency != "chaos" || offers[1].Pay.Amount != 175 ||
+		offers[1].Account.Name != "Seller2" {
+		t.Fatalf("unexpected offer: %+v", offers[1])
+	}
+}
+
+func TestExchangeWithInvalidOptions(t *testing.T) {
+	c := newTestTradeClient()
+	cases := []struct {
+		want, have []string
+		league     string
+	}{
+		{nil, []string{"chaos"}, "Standard"},
+		{[]string{"divine"}, nil, "Standard"},
+		{[]string{"divine"}, []string{"chaos"}, ""},
+	}
+	for _, tc := range cases {
+		if _, err := c.Exchange(tc.want, tc.have, tc.league); err != ErrInvalidExchange {
+			t.Fatalf("failed to detect invalid exchange request: %+v", tc)
+		}
+	}
+}
+
+func TestParseExchangeResponseWithInvalidJSON(t *testing.T) {
+	resp, err := loadFixture("fixtures/invalid.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if _, err := parseExchangeResponse(resp); err == nil {
+		t.Fatal("failed to detect invalid json")
+	}
+}
+
+func TestNewOrderBooks(t *testing.T) {
+	resp, err := loadFixture("fixtures/exchange.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	offers, err := parseExchangeResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse exchange response: %v", err)
+	}
+	offers = append(offers, ExchangeOffer{
+		ID:    "e5",
+		Pay:   ExchangeAmount{Currency: "chaos", Amount: 1},
+		Get:   ExchangeAmount{Currency: "divine", Amount: 1},
+		Stock: 0,
+	})
+
+	books := NewOrderBooks(offers)
+	if len(books) != 2 {
+		t.Fatalf("unexpected order book count: expected 2, got %d", len(books))
+	}
+	divine := books[0]
+	if divine.Pay != "chaos" || divine.Get != "divine" {
+		t.Fatalf("unexpected order book pair: %s/%s", divine.Pay, divine.Get)
+	}
+	if len(divine.Levels) != 2 {
+		t.Fatalf("unexpected level count: expected 2, got %d", len(divine.Levels))
+	}
+	best, ok := divine.Best()
+	if !ok || best.Ratio != 1.0/175 || best.Stock != 3 {
+		t.Fatalf("unexpected best level: %+v", best)
+	}
+	if l := divine.Levels[1]; l.Stock != 14 || len(l.Offers) != 2 {
+		t.Fatalf("unexpected second level: %+v", l)
+	}
+	if divine.Stock() != 17 {
+		t.Fatalf("unexpected stock: expected 17, got %d", divine.Stock())
+	}
+
+	cost, ok := divine.Cost(5)
+	if !ok || cost != 3*175+2*180 {
+		t.Fatalf("unexpected cost: %v, %v", cost, ok)
+	}
+	if _, ok := divine.Cost(18); ok {
+		t.Fatal("failed to detect insufficient stock")
+	}
+	if _, ok := (OrderBook{}).Best(); ok {
+		t.Fatal("unexpected best level in empty order book")
+	}
+}
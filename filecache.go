@@ -0,0 +1,380 @@
+package poeapi
+
+import (
+	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// fileCacheExt is the extension of cache entries. Other files in the
+	// directory are ignored.
+	fileCacheExt = ".json.gz"
+
+	// fileCacheTempPattern names entries which are still being written.
+	fileCacheTempPattern = ".tmp-*"
+
+	// fileCacheTempTTL is the age after which temporary files are assumed
+	// to have been abandoned by a crashed process, and are removed.
+	fileCacheTempTTL = time.Hour
+)
+
+// FileCacheOptions contains settings for a FileCache.
+type FileCacheOptions struct {
+	// The directory in which to store responses. It is created if it does
+	// not exist.
+	Dir string
+
+	// The maximum total size of the cache on disk, in bytes. When exceeded,
+	// the least recently used responses are removed. Zero means no limit.
+	MaxBytes int64
+}
+
+// FileCache is a Cache which stores gzipped responses on disk, so that they
+// survive restarts. Each response is stored in its own file, named by a hash
+// of its URL. Files are written to a temporary name and renamed into place,
+// so that several processes may share a directory safely: readers never see a
+// partially written response, and concurrent writes of the same URL leave one
+// complete response.
+type FileCache struct {
+	opts FileCacheOptions
+	now  func() time.Time
+
+	// Counters for Stats, which only cover this process.
+	hits, misses, evictions, rejected int64
+}
+
+// fileCacheHeader precedes the response body in each file.
+type fileCacheHeader struct {
+	Key     string `json:"key"`
+	Expires int64  `json:"expires,omitempty"`
+}
+
+// NewFileCache creates a FileCache, creating its directory if necessary.
+func NewFileCache(opts FileCacheOptions) (*FileCache, error) {
+	if opts.Dir == "" || opts.MaxBytes < 0 {
+		return nil, ErrInvalidFileCache
+	}
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, err
+	}
+	return &FileCache{opts: opts, now: time.Now}, nil
+}
+
+func (c *FileCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.opts.Dir, hex.EncodeToString(sum[:])+fileCacheExt)
+}
+
+// Get reads a response from disk. Expired and corrupt responses are removed
+// and reported as missing. Responses which cannot be read, for example due to
+// their permissions, are reported as missing but kept.
+func (c *FileCache) Get(key string) (string, error) {
+	path := c.path(key)
+	header, body, info, err := readFileCacheEntry(path, true)
+	if err != nil {
+		if errors.Is(err, errCorruptFileCacheEntry) {
+			removeFileCacheEntry(path, info)
+		}
+		atomic.AddInt64(&c.misses, 1)
+		return "", ErrNotFoundInCache
+	}
+	// Entries are only named by a hash, so guard against collisions.
+	if header.Key != key {
+		atomic.AddInt64(&c.misses, 1)
+		return "", ErrNotFoundInCache
+	}
+	now := c.now()
+	if header.Expires != 0 && !now.Before(time.Unix(0, header.Expires)) {
+		removeFileCacheEntry(path, info)
+		atomic.AddInt64(&c.misses, 1)
+		return "", ErrNotFoundInCache
+	}
+
+	// The modification time records when the entry was last used, for
+	// eviction.
+	os.Chtimes(path, now, now)
+	atomic.AddInt64(&c.hits, 1)
+	return body, nil
+}
+
+// Set writes a response to disk, then removes the least recently used
+// responses if the cache exceeds its maximum size. ErrCacheEntryTooLarge is
+// returned for responses which are larger than the maximum size once
+// compressed.
+func (c *FileCache) Set(key, value string, ttl time.Duration) error {
+	now := c.now()
+	header := fileCacheHeader{Key: key}
+	if ttl > 0 {
+		header.Expires = now.Add(ttl).UnixNano()
+	}
+
+	f, err := ioutil.TempFile(c.opts.Dir, fileCacheTempPattern)
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if err := writeFileCacheEntry(f, header, value); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	info, err := f.Stat()
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if c.opts.MaxBytes > 0 && info.Size() > c.opts.MaxBytes {
+		os.Remove(tmp)
+		atomic.AddInt64(&c.rejected, 1)
+		return ErrCacheEntryTooLarge
+	}
+
+	os.Chtimes(tmp, now, now)
+	if err := os.Rename(tmp, c.path(key)); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return c.evict()
+}
+
+// Delete removes a response from disk.
+func (c *FileCache) Delete(key string) error {
+	if err := os.Remove(c.path(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// Invalidate removes every response whose URL path begins with prefix. Since
+// files are named by hash, each file's header must be read to find its URL.
+func (c *FileCache) Invalidate(prefix string) int {
+	entries, err := c.entries()
+	if err != nil {
+		return 0
+	}
+	removed := 0
+	for _, e := range entries {
+		header, _, _, err := readFileCacheEntry(e.path, false)
+		if err != nil || !strings.HasPrefix(cachePath(header.Key), prefix) {
+			continue
+		}
+		if os.Remove(e.path) == nil {
+			removed++
+		}
+	}
+	return removed
+}
+
+// Size returns the total size of the cached responses on disk, in bytes.
+func (c *FileCache) Size() (int64, error) {
+	entries, err := c.entries()
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	return total, nil
+}
+
+// Stats returns the cache's activity in this process, and the number and
+// size of the responses on disk, which may have been written by any process.
+func (c *FileCache) Stats() CacheStats {
+	s := CacheStats{
+		Hits:      atomic.LoadInt64(&c.hits),
+		Misses:    atomic.LoadInt64(&c.misses),
+		Evictions: atomic.LoadInt64(&c.evictions),
+		Rejected:  atomic.LoadInt64(&c.rejected),
+	}
+	if entries, err := c.entries(); err == nil {
+		s.Entries = len(entries)
+		for _, e := range entries {
+			s.Bytes += e.size
+		}
+	}
+	return s
+}
+
+type fileCacheEntry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists the cached responses on disk, removing abandoned temporary
+// files along the way. Files removed by other processes during the listing
+// are skipped.
+func (c *FileCache) entries() ([]fileCacheEntry, error) {
+	dir, err := os.ReadDir(c.opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]fileCacheEntry, 0, len(dir))
+	for _, d := range dir {
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(c.opts.Dir, d.Name())
+		if strings.HasPrefix(d.Name(), ".tmp-") {
+			if c.now().Sub(info.ModTime()) > fileCacheTempTTL {
+				os.Remove(path)
+			}
+			continue
+		}
+		if !strings.HasSuffix(d.Name(), fileCacheExt) {
+			continue
+		}
+		entries = append(entries, fileCacheEntry{
+			path:    path,
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return entries, nil
+}
+
+// evict removes the least recently used responses until the cache fits
+// within its maximum size. The directory is scanned each time, since other
+// processes may have added or removed responses.
+func (c *FileCache) evict() error {
+	if c.opts.MaxBytes == 0 {
+		return nil
+	}
+	entries, err := c.entries()
+	if err != nil {
+		return err
+	}
+	var total int64
+	for _, e := range entries {
+		total += e.size
+	}
+	if total <= c.opts.MaxBytes {
+		return nil
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= c.opts.MaxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		atomic.AddInt64(&c.evictions, 1)
+		total -= e.size
+	}
+	return nil
+}
+
+// writeFileCacheEntry writes a header line and the response body to f,
+// compressed with gzip.
+func writeFileCacheEntry(f *os.File, header fileCacheHeader, body string) error {
+	zw := gzip.NewWriter(f)
+	if err := json.NewEncoder(zw).Encode(header); err != nil {
+		return err
+	}
+	if _, err := zw.Write([]byte(body)); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// errCorruptFileCacheEntry is returned by readFileCacheEntry when a file was
+// read successfully but could not be decoded.
+var errCorruptFileCacheEntry = errors.New("corrupt file cache entry")
+
+// readFileCacheEntry reads the header of a cache file, and its body if
+// withBody is set. The file's info, as of when it was opened, is returned so
+// that it may later be removed safely.
+func readFileCacheEntry(path string, withBody bool) (fileCacheHeader, string, os.FileInfo, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return fileCacheHeader{}, "", nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fileCacheHeader{}, "", nil, err
+	}
+
+	// Errors from the file itself are distinguished from decoding errors, so
+	// that entries are only removed when they are corrupt.
+	fr := &fileCacheReader{r: f}
+	fail := func(err error) (fileCacheHeader, string, os.FileInfo, error) {
+		if fr.err != nil {
+			return fileCacheHeader{}, "", info, fr.err
+		}
+		return fileCacheHeader{}, "", info, fmt.Errorf("%w: %v", errCorruptFileCacheEntry, err)
+	}
+
+	zr, err := gzip.NewReader(fr)
+	if err != nil {
+		return fail(err)
+	}
+	r := bufio.NewReader(zr)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return fail(err)
+	}
+	var header fileCacheHeader
+	if err := json.Unmarshal(line, &header); err != nil {
+		return fail(err)
+	}
+	if !withBody {
+		return header, "", info, nil
+	}
+
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return fail(err)
+	}
+	return header, string(body), info, nil
+}
+
+// fileCacheReader records the first error, other than io.EOF, returned by the
+// underlying file.
+type fileCacheReader struct {
+	r   io.Reader
+	err error
+}
+
+func (fr *fileCacheReader) Read(p []byte) (int, error) {
+	n, err := fr.r.Read(p)
+	if err != nil && err != io.EOF && fr.err == nil {
+		fr.err = err
+	}
+	return n, err
+}
+
+// removeFileCacheEntry removes the file at path only if it is still the file
+// described by info. Another process may have renamed a fresh entry into
+// place since info was taken, and that entry must be kept.
+func removeFileCacheEntry(path string, info os.FileInfo) {
+	current, err := os.Stat(path)
+	if err != nil || !os.SameFile(info, current) {
+		return
+	}
+	os.Remove(path)
+}
@@ -0,0 +1,295 @@
+package poeapi
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestFileCache(t *testing.T, maxBytes int64) *FileCache {
+	c, err := NewFileCache(FileCacheOptions{Dir: t.TempDir(), MaxBytes: maxBytes})
+	if err != nil {
+		t.Fatalf("failed to create file cache: %v", err)
+	}
+	return c
+}
+
+func TestNewFileCacheWithInvalidOptions(t *testing.T) {
+	if _, err := NewFileCache(FileCacheOptions{}); err != ErrInvalidFileCache {
+		t.Fatal("failed to detect missing cache directory")
+	}
+	if _, err := NewFileCache(FileCacheOptions{Dir: t.TempDir(), MaxBytes: -1}); err != ErrInvalidFileCache {
+		t.Fatal("failed to detect invalid cache size")
+	}
+}
+
+func TestFileCache(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	url := "https://api.pathofexile.com/ladders/Standard?limit=200"
+	if _, err := c.Get(url); err != ErrNotFoundInCache {
+		t.Fatal("found response in empty cache")
+	}
+	if err := c.Set(url, `{"entries":[]}`, 0); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	body, err := c.Get(url)
+	if err != nil {
+		t.Fatalf("failed to read from cache: %v", err)
+	}
+	if body != `{"entries":[]}` {
+		t.Fatalf("unexpected cached response: %s", body)
+	}
+
+	// A second cache in the same directory, as in another process, sees the
+	// same responses.
+	other, err := NewFileCache(c.opts)
+	if err != nil {
+		t.Fatalf("failed to open file cache: %v", err)
+	}
+	if _, err := other.Get(url); err != nil {
+		t.Fatalf("failed to read from shared cache: %v", err)
+	}
+
+	if err := c.Delete(url); err != nil {
+		t.Fatalf("failed to delete from cache: %v", err)
+	}
+	if _, err := other.Get(url); err != ErrNotFoundInCache {
+		t.Fatal("failed to delete response")
+	}
+	if err := c.Delete(url); err != nil {
+		t.Fatalf("failed to delete missing response: %v", err)
+	}
+}
+
+func TestFileCacheCompression(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	body := strings.Repeat(`{"name":"Standard"},`, 1000)
+	if err := c.Set("https://api.pathofexile.com/leagues", body, 0); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	size, err := c.Size()
+	if err != nil {
+		t.Fatalf("failed to measure cache: %v", err)
+	}
+	if size == 0 || size >= int64(len(body)/10) {
+		t.Fatalf("failed to compress response: %d bytes on disk", size)
+	}
+}
+
+func TestFileCacheExpiry(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	now := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	if err := c.Set("foo", "bar", time.Minute); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	if _, err := c.Get("foo"); err != nil {
+		t.Fatalf("failed to get unexpired response: %v", err)
+	}
+	now = now.Add(time.Minute)
+	if _, err := c.Get("foo"); err != ErrNotFoundInCache {
+		t.Fatal("failed to expire response")
+	}
+	if _, err := os.Stat(c.path("foo")); !os.IsNotExist(err) {
+		t.Fatal("failed to remove expired response")
+	}
+}
+
+func TestFileCacheCorruptEntry(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	if err := ioutil.WriteFile(c.path("foo"), []byte("not gzip"), 0644); err != nil {
+		t.Fatalf("failed to write corrupt entry: %v", err)
+	}
+	if _, err := c.Get("foo"); err != ErrNotFoundInCache {
+		t.Fatal("failed to ignore corrupt entry")
+	}
+	if _, err := os.Stat(c.path("foo")); !os.IsNotExist(err) {
+		t.Fatal("failed to remove corrupt entry")
+	}
+}
+
+func TestFileCacheUnreadableEntry(t *testing.T) {
+	// Reading a directory fails with an I/O error rather than a decoding
+	// error, even when running as root.
+	c := newTestFileCache(t, 0)
+	if err := os.Mkdir(c.path("foo"), 0755); err != nil {
+		t.Fatalf("failed to create unreadable entry: %v", err)
+	}
+	if _, err := c.Get("foo"); err != ErrNotFoundInCache {
+		t.Fatal("failed to ignore unreadable entry")
+	}
+	if _, err := os.Stat(c.path("foo")); err != nil {
+		t.Fatal("removed unreadable entry")
+	}
+}
+
+func TestFileCacheReplacedEntry(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	if err := c.Set("foo", "old", 0); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	info, err := os.Stat(c.path("foo"))
+	if err != nil {
+		t.Fatalf("failed to stat entry: %v", err)
+	}
+
+	// Another process replaces the entry before this one removes it.
+	other, _ := NewFileCache(c.opts)
+	if err := other.Set("foo", "new", 0); err != nil {
+		t.Fatalf("failed to replace entry: %v", err)
+	}
+	removeFileCacheEntry(c.path("foo"), info)
+	if body, err := c.Get("foo"); err != nil || body != "new" {
+		t.Fatalf("removed replaced entry: %q (%v)", body, err)
+	}
+}
+
+func TestFileCacheEviction(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	now := time.Date(2023, 8, 20, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+
+	// Measure a single entry to size the cache for three of them.
+	if err := c.Set("0", strings.Repeat("a", 100), 0); err != nil {
+		t.Fatalf("failed to write to cache: %v", err)
+	}
+	size, err := c.Size()
+	if err != nil {
+		t.Fatalf("failed to measure cache: %v", err)
+	}
+	c.opts.MaxBytes = 3 * size
+
+	for i := 1; i <= 3; i++ {
+		now = now.Add(time.Second)
+		key := fmt.Sprint(i)
+		if err := c.Set(key, strings.Repeat("a", 100), 0); err != nil {
+			t.Fatalf("failed to write to cache: %v", err)
+		}
+		if i == 1 {
+			// Use the oldest entry so that it is not evicted.
+			now = now.Add(time.Second)
+			if _, err := c.Get("0"); err != nil {
+				t.Fatalf("failed to read from cache: %v", err)
+			}
+		}
+	}
+
+	if _, err := c.Get("1"); err != ErrNotFoundInCache {
+		t.Fatal("failed to evict least recently used response")
+	}
+	for _, key := range []string{"0", "2", "3"} {
+		if _, err := c.Get(key); err != nil {
+			t.Fatalf("evicted recently used response %s", key)
+		}
+	}
+	if size, _ := c.Size(); size > c.opts.MaxBytes {
+		t.Fatalf("cache exceeds maximum size: %d bytes", size)
+	}
+	if s := c.Stats(); s.Evictions != 1 || s.Entries != 3 || s.Hits != 4 || s.Misses != 1 {
+		t.Fatalf("unexpected cache stats: %+v", s)
+	}
+
+	// Responses larger than the cache are not stored.
+	c.opts.MaxBytes = 10
+	if err := c.Set("large", strings.Repeat("a", 100), 0); err != ErrCacheEntryTooLarge {
+		t.Fatalf("failed to reject large response: %v", err)
+	}
+	if _, err := c.Get("large"); err != ErrNotFoundInCache {
+		t.Fatal("stored response larger than cache")
+	}
+}
+
+func TestFileCacheInvalidate(t *testing.T) {
+	c := newTestFileCache(t, 0)
+	c.Set("https://api.pathofexile.com/leagues", "A", 0)
+	c.Set("https://api.pathofexile.com/leagues/Standard", "B", 0)
+	c.Set("https://api.pathofexile.com/ladders/Standard", "C", 0)
+
+	if n := c.Invalidate("/leagues"); n != 2 {
+		t.Fatalf("unexpected invalidation count: expected 2, got %d", n)
+	}
+	if _, err := c.Get("https://api.pathofexile.com/ladders/Standard"); err != nil {
+		t.Fatalf("invalidated unrelated response: %v", err)
+	}
+}
+
+func TestFileCacheAbandonedTempFiles(t *testing.T) {
+	c := newTestFileCache(t, 1<<20)
+	tmp := filepath.Join(c.opts.Dir, ".tmp-123")
+	if err := ioutil.WriteFile(tmp, []byte("partial"), 0644); err != nil {
+		t.Fatalf("failed to write temporary file: %v", err)
+	}
+	old := time.Now().Add(-2 * fileCacheTempTTL)
+	os.Chtimes(tmp, old, old)
+
+	c.Set("foo", "bar", 0)
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Fatal("failed to remove abandoned temporary file")
+	}
+}
+
+func TestFileCacheConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Separate caches share the directory, as separate processes
+			// would.
+			c, err := NewFileCache(FileCacheOptions{Dir: dir, MaxBytes: 1 << 20})
+			if err != nil {
+				t.Errorf("failed to create file cache: %v", err)
+				return
+			}
+			for j := 0; j < 20; j++ {
+				c.Set("shared", strings.Repeat(fmt.Sprint(i), 1000), 0)
+				if body, err := c.Get("shared"); err == nil && len(body) != 1000 {
+					t.Errorf("read partially written response of %d bytes", len(body))
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("failed to list cache directory: %v", err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("unexpected file count: expected 1, got %d", len(files))
+	}
+}
+
+func TestClientWithFileCache(t *testing.T) {
+	cache := newTestFileCache(t, 0)
+	opts := DefaultClientOptions
+	opts.Host = testHost
+	opts.UseSSL = false
+	opts.UseDNSCache = false
+	opts.CacheSize = 0
+	opts.Cache = cache
+	opts.RateLimit = UnlimitedRate
+	opts.StashRateLimit = UnlimitedRate
+	opts.RequestTimeout = testTimeout
+
+	c, err := NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client with file cache: %v", err)
+	}
+	if _, err := c.GetLeague(GetLeagueOptions{ID: "Standard"}); err != nil {
+		t.Fatalf("failed to get league: %v", err)
+	}
+	if size, _ := cache.Size(); size == 0 {
+		t.Fatal("failed to cache response on disk")
+	}
+	if n := c.Invalidate(leaguesEndpoint); n != 1 {
+		t.Fatalf("unexpected invalidation count: expected 1, got %d", n)
+	}
+}
@@ -0,0 +1,451 @@
+package poeapi
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// The clipboard format divides item text into sections with this line.
+const itemTextSeparator = "--------"
+
+// Suffixes which mark the type of a mod or value in item text.
+const (
+	implicitSuffix  = " (implicit)"
+	enchantSuffix   = " (enchant)"
+	craftedSuffix   = " (crafted)"
+	fracturedSuffix = " (fractured)"
+	augmentedSuffix = " (augmented)"
+	unmetSuffix     = " (unmet)"
+)
+
+// itemTextMarkup matches the formatting tags which the API sometimes includes
+// in item names, such as "<<set:MS>><<set:M>><<set:S>>".
+var itemTextMarkup = regexp.MustCompile(`<<[^>]*>>`)
+
+// itemDescriptions are the opening words of the instructions found in
+// Item.DescrText, which are used to tell them apart from flavour text when
+// parsing.
+var itemDescriptions = []string{
+	"Place into",
+	"Right click",
+	"Shift click",
+	"Travel to this Map",
+	"Can be used",
+	"Use this",
+	"Give this",
+}
+
+// FormatText renders the item in the text format which the game copies to the
+// clipboard when pressing Ctrl+C over an item, such as:
+//
+//	Rarity: Unique
+//	Command of the Pit
+//	Riveted Gloves
+//	--------
+//	Quality: +20% (augmented)
+//	--------
+//	Requirements:
+//	Level: 60
+//	--------
+//	Item Level: 85
+//	--------
+//	Note: ~price 15 exa
+//
+// The item class line which the game adds is omitted, since it cannot be
+// derived from the API.
+func (i Item) FormatText() string {
+	var sections [][]string
+
+	header := []string{"Rarity: " + i.Frame().String()}
+	if name := stripMarkup(i.Name); name != "" {
+		header = append(header, name)
+	}
+	sections = append(sections, append(header, stripMarkup(i.TypeLine)))
+
+	if len(i.Properties) > 0 {
+		sections = append(sections, formatProperties(i.Properties))
+	}
+	if len(i.Requirements) > 0 {
+		lines := []string{"Requirements:"}
+		for _, r := range i.Requirements {
+			lines = append(lines, formatProperty(Property{Name: r.Name, Values: r.Values}))
+		}
+		sections = append(sections, lines)
+	}
+	if len(i.Sockets) > 0 {
+		sections = append(sections, []string{"Sockets: " + i.SocketString()})
+	}
+	if i.ItemLevel > 0 {
+		sections = append(sections, []string{"Item Level: " + strconv.FormatInt(i.ItemLevel, 10)})
+	}
+	if i.SecDescrText != "" {
+		sections = append(sections, splitLines(i.SecDescrText))
+	}
+	if len(i.EnchantMods) > 0 {
+		sections = append(sections, suffixLines(i.EnchantMods, enchantSuffix))
+	}
+	if len(i.ImplicitMods) > 0 {
+		sections = append(sections, suffixLines(i.ImplicitMods, implicitSuffix))
+	}
+	if !i.Identified {
+		sections = append(sections, []string{"Unidentified"})
+	} else if mods := i.explicitLines(); len(mods) > 0 {
+		sections = append(sections, mods)
+	}
+	if len(i.AdditionalProperties) > 0 {
+		sections = append(sections, formatProperties(i.AdditionalProperties))
+	}
+	if len(i.FlavorText) > 0 {
+		var lines []string
+		for _, line := range i.FlavorText {
+			lines = append(lines, splitLines(line)...)
+		}
+		sections = append(sections, lines)
+	}
+	if flags := i.flagLines(); len(flags) > 0 {
+		sections = append(sections, flags)
+	}
+	if i.DescrText != "" {
+		sections = append(sections, splitLines(i.DescrText))
+	}
+	if i.Note != "" {
+		sections = append(sections, []string{"Note: " + i.Note})
+	}
+
+	var b strings.Builder
+	for n, lines := range sections {
+		if n > 0 {
+			b.WriteString(itemTextSeparator + "\n")
+		}
+		for _, line := range lines {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// explicitLines returns the lines of the explicit mods section, in the order
+// the game displays them.
+func (i Item) explicitLines() []string {
+	var lines []string
+	lines = append(lines, suffixLines(i.FracturedMods, fracturedSuffix)...)
+	for _, mod := range i.ExplicitMods {
+		lines = append(lines, splitLines(mod)...)
+	}
+	lines = append(lines, suffixLines(i.CraftedMods, craftedSuffix)...)
+	return lines
+}
+
+// itemFlag is a line of item text which sets a boolean field.
+type itemFlag struct {
+	text  string
+	field func(*Item) *bool
+}
+
+var itemFlags = []itemFlag{
+	{"Shaper Item", func(i *Item) *bool { return &i.Influences.Shaper }},
+	{"Elder Item", func(i *Item) *bool { return &i.Influences.Elder }},
+	{"Crusader Item", func(i *Item) *bool { return &i.Influences.Crusader }},
+	{"Redeemer Item", func(i *Item) *bool { return &i.Influences.Redeemer }},
+	{"Hunter Item", func(i *Item) *bool { return &i.Influences.Hunter }},
+	{"Warlord Item", func(i *Item) *bool { return &i.Influences.Warlord }},
+	{"Synthesised Item", func(i *Item) *bool { return &i.Synthesised }},
+	{"Fractured Item", func(i *Item) *bool { return &i.Fractured }},
+	{"Split", func(i *Item) *bool { return &i.Split }},
+	{"Mirrored", func(i *Item) *bool { return &i.Duplicated }},
+	{"Corrupted", func(i *Item) *bool { return &i.Corrupted }},
+}
+
+// flagLines returns a line for each flag set on the item. The legacy Shaper
+// and Elder fields are treated as influences.
+func (i Item) flagLines() []string {
+	item := i
+	item.Influences.Shaper = item.Influences.Shaper || item.Shaper
+	item.Influences.Elder = item.Influences.Elder || item.Elder
+	item.Duplicated = item.Duplicated || item.Mirrored
+
+	var lines []string
+	for _, f := range itemFlags {
+		if *f.field(&item) {
+			lines = append(lines, f.text)
+		}
+	}
+	return lines
+}
+
+func formatProperties(properties []Property) []string {
+	lines := make([]string, 0, len(properties))
+	for _, p := range properties {
+		lines = append(lines, formatProperty(p))
+	}
+	return lines
+}
+
+// formatProperty renders a property as a line of item text. Properties with
+// display mode 3 are format strings, such as "Can Store %0 Uses", where each
+// placeholder is replaced by a value.
+func formatProperty(p Property) string {
+	if len(p.Values) == 0 {
+		return p.Name
+	}
+	if p.DisplayMode == 3 {
+		line := p.Name
+		for n, v := range p.TypedValues() {
+			line = strings.ReplaceAll(line, "%"+strconv.Itoa(n), v.Text)
+		}
+		return line
+	}
+	values := make([]string, 0, len(p.Values))
+	for _, v := range p.TypedValues() {
+		switch v.Type {
+		case ValueDefault, ValuePhysical:
+			values = append(values, v.Text)
+		case ValueUnmet:
+			values = append(values, v.Text+unmetSuffix)
+		default:
+			values = append(values, v.Text+augmentedSuffix)
+		}
+	}
+	return p.Name + ": " + strings.Join(values, ", ")
+}
+
+func suffixLines(lines []string, suffix string) []string {
+	out := make([]string, 0, len(lines))
+	for _, line := range lines {
+		for _, l := range splitLines(line) {
+			out = append(out, l+suffix)
+		}
+	}
+	return out
+}
+
+// splitLines splits text on newlines, removing the carriage returns which the
+// API includes in some fields.
+func splitLines(s string) []string {
+	s = strings.ReplaceAll(s, "\r", "")
+	return strings.Split(strings.TrimRight(s, "\n"), "\n")
+}
+
+func stripMarkup(s string) string {
+	return itemTextMarkup.ReplaceAllString(s, "")
+}
+
+// ParseItemText parses item text in the format copied from the game, as
+// described by FormatText. Fields which the format does not contain, such as
+// the item's ID, are left empty.
+//
+// Sections without a marker are assigned by position: the first is the
+// item's explicit mods (after the skill description for gems) and any others
+// are flavour text, unless they begin like the instructions in DescrText. A
+// unique item without explicit mods therefore has its flavour text parsed as
+// mods.
+func ParseItemText(text string) (Item, error) {
+	text = strings.ReplaceAll(text, "\r", "")
+	var sections [][]string
+	for _, section := range strings.Split(text, itemTextSeparator) {
+		var lines []string
+		for _, line := range strings.Split(section, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			sections = append(sections, lines)
+		}
+	}
+	if len(sections) == 0 {
+		return Item{}, ErrInvalidItemText
+	}
+
+	item := Item{Identified: true}
+	if err := parseItemHeader(&item, sections[0]); err != nil {
+		return Item{}, err
+	}
+
+	var (
+		free     [][]string
+		explicit bool
+	)
+	for n, lines := range sections[1:] {
+		switch {
+		case lines[0] == "Requirements:":
+			for _, line := range lines[1:] {
+				p := parseProperty(line)
+				item.Requirements = append(item.Requirements,
+					Requirement{Name: p.Name, Values: p.Values})
+			}
+		case strings.HasPrefix(lines[0], "Sockets: "):
+			sockets, err := ParseSockets(strings.TrimPrefix(lines[0], "Sockets: "))
+			if err != nil {
+				return Item{}, err
+			}
+			item.Sockets = sockets
+		case strings.HasPrefix(lines[0], "Item Level: "):
+			ilvl, err := strconv.ParseInt(strings.TrimPrefix(lines[0], "Item Level: "), 10, 64)
+			if err != nil {
+				return Item{}, ErrInvalidItemText
+			}
+			item.ItemLevel = ilvl
+		case strings.HasPrefix(lines[0], "Note: "):
+			item.Note = strings.TrimPrefix(lines[0], "Note: ")
+		case strings.HasPrefix(lines[0], "Experience: "):
+			for _, line := range lines {
+				p := parseProperty(line)
+				p.DisplayMode = 2
+				item.AdditionalProperties = append(item.AdditionalProperties, p)
+			}
+		case len(lines) == 1 && lines[0] == "Unidentified":
+			item.Identified = false
+		case isFlagSection(lines):
+			for _, line := range lines {
+				for _, f := range itemFlags {
+					if line == f.text {
+						*f.field(&item) = true
+					}
+				}
+			}
+		case isModSection(lines):
+			explicit = item.parseMods(lines) || explicit
+		case n == 0:
+			item.Properties = make([]Property, 0, len(lines))
+			for _, line := range lines {
+				item.Properties = append(item.Properties, parseProperty(line))
+			}
+		case isDescription(lines):
+			item.DescrText = strings.Join(lines, "\n")
+		default:
+			free = append(free, lines)
+		}
+	}
+
+	if item.Frame() == FrameGem && len(free) > 0 {
+		item.SecDescrText = strings.Join(free[0], "\n")
+		free = free[1:]
+	}
+	if item.Identified && !explicit && len(free) > 0 {
+		item.parseMods(free[0])
+		free = free[1:]
+	}
+	for _, lines := range free {
+		item.FlavorText = append(item.FlavorText, lines...)
+	}
+	return item, nil
+}
+
+// parseItemHeader parses the rarity, name and type lines of item text.
+func parseItemHeader(item *Item, lines []string) error {
+	if strings.HasPrefix(lines[0], "Item Class: ") {
+		lines = lines[1:]
+	}
+	if len(lines) < 2 || !strings.HasPrefix(lines[0], "Rarity: ") {
+		return ErrInvalidItemText
+	}
+	rarity := strings.TrimPrefix(lines[0], "Rarity: ")
+	frame, ok := parseFrameType(rarity)
+	if !ok {
+		return ErrInvalidItemText
+	}
+	item.FrameType = int64(frame)
+	item.Rarity = rarity
+
+	switch len(lines) {
+	case 2:
+		item.TypeLine = lines[1]
+	case 3:
+		item.Name = lines[1]
+		item.TypeLine = lines[2]
+	default:
+		return ErrInvalidItemText
+	}
+	return nil
+}
+
+func parseFrameType(name string) (FrameType, bool) {
+	for frame, n := range frameTypeNames {
+		if n == name {
+			return frame, true
+		}
+	}
+	return 0, false
+}
+
+// parseProperty parses a line of item text into a property. Lines without a
+// value, such as a gem's tags, become properties with only a name.
+func parseProperty(line string) Property {
+	n := strings.Index(line, ": ")
+	if n < 0 {
+		return Property{Name: line, Values: [][]interface{}{}}
+	}
+	p := Property{Name: line[:n]}
+	for _, text := range strings.Split(line[n+2:], ", ") {
+		v := PropertyValue{Text: text}
+		if strings.HasSuffix(text, augmentedSuffix) {
+			v = PropertyValue{Text: strings.TrimSuffix(text, augmentedSuffix), Type: ValueAugmented}
+		} else if strings.HasSuffix(text, unmetSuffix) {
+			v = PropertyValue{Text: strings.TrimSuffix(text, unmetSuffix), Type: ValueUnmet}
+		}
+		p.Values = append(p.Values, v.Tuple())
+	}
+	return p
+}
+
+// parseMods adds each line to the mod list indicated by its suffix. It reports
+// whether the lines included any explicit, crafted or fractured mods.
+func (i *Item) parseMods(lines []string) bool {
+	explicit := false
+	for _, line := range lines {
+		if !strings.HasSuffix(line, implicitSuffix) && !strings.HasSuffix(line, enchantSuffix) {
+			explicit = true
+		}
+		switch {
+		case strings.HasSuffix(line, implicitSuffix):
+			i.ImplicitMods = append(i.ImplicitMods, strings.TrimSuffix(line, implicitSuffix))
+		case strings.HasSuffix(line, enchantSuffix):
+			i.EnchantMods = append(i.EnchantMods, strings.TrimSuffix(line, enchantSuffix))
+		case strings.HasSuffix(line, craftedSuffix):
+			i.CraftedMods = append(i.CraftedMods, strings.TrimSuffix(line, craftedSuffix))
+		case strings.HasSuffix(line, fracturedSuffix):
+			i.FracturedMods = append(i.FracturedMods, strings.TrimSuffix(line, fracturedSuffix))
+		default:
+			i.ExplicitMods = append(i.ExplicitMods, line)
+		}
+	}
+	return explicit
+}
+
+func isModSection(lines []string) bool {
+	for _, line := range lines {
+		for _, suffix := range []string{implicitSuffix, enchantSuffix, craftedSuffix, fracturedSuffix} {
+			if strings.HasSuffix(line, suffix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isFlagSection(lines []string) bool {
+	for _, line := range lines {
+		found := false
+		for _, f := range itemFlags {
+			if line == f.text {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func isDescription(lines []string) bool {
+	for _, prefix := range itemDescriptions {
+		if strings.HasPrefix(lines[0], prefix) {
+			return true
+		}
+	}
+	return false
+}
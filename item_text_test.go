@@ -0,0 +1,176 @@
+package poeapi
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestItemTextRoundTrip(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+
+	count := 0
+	for _, stash := range stashes.Stashes {
+		for _, item := range stash.Items {
+			count++
+			text := item.FormatText()
+			parsed, err := ParseItemText(text)
+			if err != nil {
+				t.Fatalf("failed to parse text for %s: %v\n%s", item.TypeLine, err, text)
+			}
+			if out := parsed.FormatText(); out != text {
+				t.Fatalf("failed to round trip %s. expected:\n%s\ngot:\n%s",
+					item.TypeLine, text, out)
+			}
+
+			if parsed.Name != item.Name || parsed.TypeLine != item.TypeLine ||
+				parsed.FrameType != item.FrameType || parsed.ItemLevel != item.ItemLevel ||
+				parsed.Note != item.Note || parsed.Corrupted != item.Corrupted ||
+				parsed.DescrText != item.DescrText || parsed.SecDescrText != item.SecDescrText {
+				t.Fatalf("failed to round trip fields for %s: %+v", item.TypeLine, parsed)
+			}
+			for _, mods := range [][2][]string{
+				{item.ImplicitMods, parsed.ImplicitMods},
+				{item.ExplicitMods, parsed.ExplicitMods},
+				{item.CraftedMods, parsed.CraftedMods},
+				{item.EnchantMods, parsed.EnchantMods},
+			} {
+				if len(mods[0]) != len(mods[1]) || len(mods[0]) > 0 && !reflect.DeepEqual(mods[0], mods[1]) {
+					t.Fatalf("failed to round trip mods for %s. expected %v, got %v",
+						item.TypeLine, mods[0], mods[1])
+				}
+			}
+			if parsed.SocketString() != item.SocketString() {
+				t.Fatalf("failed to round trip sockets for %s", item.TypeLine)
+			}
+			if len(parsed.Requirements) != len(item.Requirements) {
+				t.Fatalf("failed to round trip requirements for %s", item.TypeLine)
+			}
+		}
+	}
+	if count == 0 {
+		t.Fatal("failed to find items in fixture")
+	}
+}
+
+func TestFormatItemText(t *testing.T) {
+	item := Item{
+		FrameType:    int64(FrameRare),
+		Name:         "<<set:MS>><<set:M>><<set:S>>Rage Ward",
+		TypeLine:     "Astral Plate",
+		Identified:   true,
+		ItemLevel:    84,
+		Shaper:       true,
+		Corrupted:    true,
+		Properties:   []Property{{Name: "Armour", Values: [][]interface{}{{"1338", float64(ValueAugmented)}}}},
+		Requirements: []Requirement{{Name: "Level", Values: [][]interface{}{{"64", 0.0}}}},
+		ImplicitMods: []string{"+12% to all Elemental Resistances"},
+		ExplicitMods: []string{"+150 to maximum Life"},
+		CraftedMods:  []string{"+215 to Armour"},
+		Note:         "~price 2 chaos",
+	}
+	expected := strings.Join([]string{
+		"Rarity: Rare",
+		"Rage Ward",
+		"Astral Plate",
+		"--------",
+		"Armour: 1338 (augmented)",
+		"--------",
+		"Requirements:",
+		"Level: 64",
+		"--------",
+		"Item Level: 84",
+		"--------",
+		"+12% to all Elemental Resistances (implicit)",
+		"--------",
+		"+150 to maximum Life",
+		"+215 to Armour (crafted)",
+		"--------",
+		"Shaper Item",
+		"Corrupted",
+		"--------",
+		"Note: ~price 2 chaos",
+		"",
+	}, "\n")
+	if text := item.FormatText(); text != expected {
+		t.Fatalf("failed to format item text. expected:\n%s\ngot:\n%s", expected, text)
+	}
+}
+
+func TestParseGameItemText(t *testing.T) {
+	text := strings.Join([]string{
+		"Item Class: Body Armours",
+		"Rarity: Rare",
+		"Doom Shell",
+		"Vaal Regalia",
+		"--------",
+		"Energy Shield: 310 (augmented)",
+		"--------",
+		"Requirements:",
+		"Level: 68",
+		"Int: 194 (unmet)",
+		"--------",
+		"Sockets: B-B-B-B-G-R ",
+		"--------",
+		"Item Level: 86",
+		"--------",
+		"+1 to Level of Socketed Gems (enchant)",
+		"--------",
+		"+90 to maximum Life (fractured)",
+		"+30 to Intelligence",
+		"--------",
+		"Hunter Item",
+		"",
+	}, "\r\n")
+
+	item, err := ParseItemText(text)
+	if err != nil {
+		t.Fatalf("failed to parse item text: %v", err)
+	}
+	if item.Name != "Doom Shell" || item.TypeLine != "Vaal Regalia" || item.Frame() != FrameRare {
+		t.Fatalf("failed to parse item header: %+v", item)
+	}
+	if item.EnergyShield() != 310 || item.MaxLinks() != 6 || item.ItemLevel != 86 {
+		t.Fatalf("failed to parse item details: %+v", item)
+	}
+	if r, ok := item.Requirement("Intelligence"); !ok || r.Met() {
+		t.Fatalf("failed to parse unmet requirement: %+v", r)
+	}
+	if len(item.FracturedMods) != 1 || len(item.ExplicitMods) != 1 || len(item.EnchantMods) != 1 {
+		t.Fatalf("failed to parse mods: %+v", item)
+	}
+	if !item.Influences.Hunter || !item.Identified {
+		t.Fatalf("failed to parse flags: %+v", item)
+	}
+}
+
+func TestParseUnidentifiedItemText(t *testing.T) {
+	item, err := ParseItemText("Rarity: Magic\nCobalt Jewel\n--------\nItem Level: 70\n--------\nUnidentified\n")
+	if err != nil {
+		t.Fatalf("failed to parse item text: %v", err)
+	}
+	if item.Identified || item.TypeLine != "Cobalt Jewel" || item.Frame() != FrameMagic {
+		t.Fatalf("failed to parse unidentified item: %+v", item)
+	}
+}
+
+func TestParseInvalidItemText(t *testing.T) {
+	for _, text := range []string{
+		"",
+		"Kaom's Heart\nGlorious Plate",
+		"Rarity: Legendary\nKaom's Heart\nGlorious Plate",
+		"Rarity: Unique\nKaom's Heart\nGlorious Plate\n--------\nItem Level: high",
+	} {
+		if _, err := ParseItemText(text); !errors.Is(err, ErrInvalidItemText) {
+			t.Fatalf("failed to detect invalid item text %q: %v", text, err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+// Package itemfilter compiles filter expressions into predicates over items
+// from the public stash API, so that alerting rules can be kept in
+// configuration rather than code. For example:
+//
+//	league = "Standard" and name ~ "Kaom" and ilvl >= 84 and links >= 5
+//
+// Expressions compare fields with values, and may be combined with "and",
+// "or", "not", and parentheses. The supported fields are:
+//
+//	String fields:  league, name, type, note, account, character, stash
+//	Numeric fields: ilvl, links, sockets, frame
+//	Boolean fields: corrupted, identified, verified
+//	Mod fields:     explicit, implicit, mods
+//
+// String fields support =, != and the case-insensitive substring operators ~
+// and !~. Numeric fields support =, !=, <, <=, > and >=. Boolean fields
+// support = and != with true or false.
+//
+// Mod fields are matched with "has" and a mod template, where # stands for a
+// number. An optional comparison applies to the mod's value, which is the
+// average of its numbers for mods such as "Adds # to # Fire Damage":
+//
+//	explicit has "+# to maximum Life" >= 100
+package itemfilter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/willroberts/poeapi"
+	"github.com/willroberts/poeapi/mods"
+)
+
+// SyntaxError describes a problem with a filter expression.
+type SyntaxError struct {
+	// The byte offset of the problem in the expression, starting at 1.
+	Pos int
+	Msg string
+}
+
+// Error implements the error interface.
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("itemfilter: position %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Filter is a compiled filter expression. It is safe for concurrent use.
+type Filter struct {
+	expr string
+	root node
+}
+
+// Compile parses a filter expression.
+func Compile(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errorf(t.pos, "unexpected %s %q", t.kind, t.text)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// MustCompile is like Compile, but panics if the expression is invalid.
+func MustCompile(expr string) *Filter {
+	f, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+// Match reports whether an item, listed in the given stash, matches the
+// filter.
+func (f *Filter) Match(item poeapi.Item, stash poeapi.Stash) bool {
+	return f.root.eval(item, stash)
+}
+
+// String returns the source expression.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+type node interface {
+	eval(poeapi.Item, poeapi.Stash) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	return n.left.eval(i, s) && n.right.eval(i, s)
+}
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	return n.left.eval(i, s) || n.right.eval(i, s)
+}
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	return !n.operand.eval(i, s)
+}
+
+type stringNode struct {
+	field func(poeapi.Item, poeapi.Stash) string
+	op    string
+	value string
+}
+
+func (n stringNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	v := n.field(i, s)
+	switch n.op {
+	case "=":
+		return v == n.value
+	case "!=":
+		return v != n.value
+	case "~":
+		return strings.Contains(strings.ToLower(v), n.value)
+	case "!~":
+		return !strings.Contains(strings.ToLower(v), n.value)
+	}
+	return false
+}
+
+type numberNode struct {
+	field func(poeapi.Item, poeapi.Stash) float64
+	op    string
+	value float64
+}
+
+func (n numberNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	return compare(n.field(i, s), n.op, n.value)
+}
+
+type boolNode struct {
+	field func(poeapi.Item, poeapi.Stash) bool
+	value bool
+}
+
+func (n boolNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	return n.field(i, s) == n.value
+}
+
+type modNode struct {
+	field    func(poeapi.Item) []string
+	template string
+	op       string // Empty when the mod only needs to be present.
+	value    float64
+}
+
+func (n modNode) eval(i poeapi.Item, s poeapi.Stash) bool {
+	for _, line := range n.field(i) {
+		mod := mods.Parse(line, "")
+		if !strings.EqualFold(mod.Template, n.template) {
+			continue
+		}
+		if n.op == "" || compare(mod.Value(), n.op, n.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compare(a float64, op string, b float64) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+var stringFields = map[string]func(poeapi.Item, poeapi.Stash) string{
+	"league":    func(i poeapi.Item, s poeapi.Stash) string { return i.League },
+	"name":      func(i poeapi.Item, s poeapi.Stash) string { return i.Name },
+	"type":      func(i poeapi.Item, s poeapi.Stash) string { return i.TypeLine },
+	"note":      func(i poeapi.Item, s poeapi.Stash) string { return i.Note },
+	"account":   func(i poeapi.Item, s poeapi.Stash) string { return s.AccountName },
+	"character": func(i poeapi.Item, s poeapi.Stash) string { return s.LastCharacterName },
+	"stash":     func(i poeapi.Item, s poeapi.Stash) string { return s.Index },
+}
+
+var numberFields = map[string]func(poeapi.Item, poeapi.Stash) float64{
+	"ilvl":    func(i poeapi.Item, s poeapi.Stash) float64 { return float64(i.ItemLevel) },
+	"links":   func(i poeapi.Item, s poeapi.Stash) float64 { return float64(i.MaxLinks()) },
+	"sockets": func(i poeapi.Item, s poeapi.Stash) float64 { return float64(len(i.Sockets)) },
+	"frame":   func(i poeapi.Item, s poeapi.Stash) float64 { return float64(i.FrameType) },
+}
+
+var boolFields = map[string]func(poeapi.Item, poeapi.Stash) bool{
+	"corrupted":  func(i poeapi.Item, s poeapi.Stash) bool { return i.Corrupted },
+	"identified": func(i poeapi.Item, s poeapi.Stash) bool { return i.Identified },
+	"verified":   func(i poeapi.Item, s poeapi.Stash) bool { return i.Verified },
+}
+
+var modFields = map[string]func(poeapi.Item) []string{
+	"explicit": func(i poeapi.Item) []string { return i.ExplicitMods },
+	"implicit": func(i poeapi.Item) []string { return i.ImplicitMods },
+	"mods": func(i poeapi.Item) []string {
+		return append(append([]string{}, i.ImplicitMods...), i.ExplicitMods...)
+	},
+}
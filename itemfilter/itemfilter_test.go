@@ -0,0 +1,118 @@
+package itemfilter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/willroberts/poeapi"
+)
+
+var (
+	testStash = poeapi.Stash{
+		AccountName:       "Account1",
+		LastCharacterName: "Character1",
+		Index:             "~price 1 exa",
+	}
+	testItem = poeapi.Item{
+		Name:         "Kaom's Heart",
+		TypeLine:     "Glorious Plate",
+		League:       "Standard",
+		ItemLevel:    86,
+		Identified:   true,
+		Note:         "~b/o 5 chaos",
+		FrameType:    3,
+		ImplicitMods: []string{},
+		ExplicitMods: []string{
+			"+20% to Fire Resistance",
+			"+500 to maximum Life",
+			"Adds 10 to 20 Fire Damage to Attacks",
+		},
+		Sockets: []poeapi.Socket{
+			{Attribute: "S", Group: 0},
+			{Attribute: "S", Group: 0},
+			{Attribute: "D", Group: 0},
+			{Attribute: "I", Group: 0},
+			{Attribute: "I", Group: 0},
+			{Attribute: "D", Group: 1},
+		},
+	}
+)
+
+func TestMatch(t *testing.T) {
+	cases := map[string]bool{
+		`league = "Standard" and name ~ "kaom" and ilvl >= 84 and links >= 5`: true,
+		`league = "Hardcore"`:                                false,
+		`league != "Hardcore"`:                               true,
+		`name !~ "kaom"`:                                     false,
+		`type = "Glorious Plate"`:                            true,
+		`sockets = 6 and links = 5`:                          true,
+		`links > 5`:                                          false,
+		`corrupted = false and identified = true`:            true,
+		`corrupted != false`:                                 false,
+		`explicit has "+# to maximum Life" >= 100`:           true,
+		`explicit has "+# to maximum life" > 500`:            false,
+		`explicit has "+#% to Fire Resistance"`:              true,
+		`implicit has "+#% to Fire Resistance"`:              false,
+		`mods has "Adds # to # Fire Damage to Attacks" = 15`: true,
+		`account = "Account1" and character = "Character1"`:  true,
+		`stash ~ "exa" and note ~ "chaos"`:                   true,
+		`not (ilvl < 80 or frame != 3)`:                      true,
+		`ilvl < 80 or frame = 3 and name = "x"`:              false,
+		`NAME ~ "heart" AND NOT corrupted = true`:            true,
+	}
+	for expr, expected := range cases {
+		f, err := Compile(expr)
+		if err != nil {
+			t.Fatalf("failed to compile %s: %v", expr, err)
+		}
+		if f.Match(testItem, testStash) != expected {
+			t.Fatalf("unexpected result for %s: expected %v", expr, expected)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	cases := map[string]int{
+		`name = `:                 8,
+		`name = 5`:                8,
+		`ilvl >= "84"`:            9,
+		`ilvl ~ 84`:               6,
+		`color = "red"`:           1,
+		`name = "Kaom`:            8,
+		`(name = "a"`:             12,
+		`name = "a" name = "b"`:   12,
+		`corrupted = yes`:         13,
+		`explicit = "Life"`:       10,
+		`explicit has "Life" ~ 5`: 21,
+		`name # "a"`:              6,
+		`name = "a" and`:          15,
+		`corrupted >= true`:       11,
+	}
+	for expr, pos := range cases {
+		_, err := Compile(expr)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Fatalf("failed to detect error in %s", expr)
+		}
+		if syntaxErr.Pos != pos {
+			t.Fatalf("unexpected error position for %s: expected %d, got %d (%v)",
+				expr, pos, syntaxErr.Pos, err)
+		}
+	}
+}
+
+func TestMustCompilePanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("failed to panic on invalid expression")
+		}
+	}()
+	MustCompile("name =")
+}
+
+func TestFilterString(t *testing.T) {
+	expr := `name = "Kaom's Heart"`
+	if s := MustCompile(expr).String(); s != expr {
+		t.Fatalf("unexpected filter string: %s", s)
+	}
+}
@@ -0,0 +1,135 @@
+package itemfilter
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenString
+	tokenNumber
+	tokenOperator
+	tokenLParen
+	tokenRParen
+)
+
+func (k tokenKind) String() string {
+	switch k {
+	case tokenEOF:
+		return "end of expression"
+	case tokenIdent:
+		return "identifier"
+	case tokenString:
+		return "string"
+	case tokenNumber:
+		return "number"
+	case tokenOperator:
+		return "operator"
+	case tokenLParen:
+		return "'('"
+	case tokenRParen:
+		return "')'"
+	default:
+		return "unknown token"
+	}
+}
+
+// token is a lexical token. Pos is the byte offset of the token in the
+// expression, starting at 1.
+type token struct {
+	kind  tokenKind
+	text  string
+	value string // Unquoted contents of string tokens.
+	pos   int
+}
+
+// operators lists the comparison operators, longest first so that "<=" is
+// matched before "<".
+var operators = []string{"!=", "!~", "<=", ">=", "=", "~", "<", ">"}
+
+// lex splits an expression into tokens.
+func lex(expr string) ([]token, error) {
+	tokens := make([]token, 0)
+	i := 0
+	for i < len(expr) {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i + 1})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i + 1})
+			i++
+		case c == '"':
+			t, n, err := lexString(expr, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			i += n
+		case c == '-' || c == '.' || unicode.IsDigit(c):
+			start := i
+			i++
+			for i < len(expr) && (expr[i] == '.' || unicode.IsDigit(rune(expr[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: expr[start:i], pos: start + 1})
+		case unicode.IsLetter(c) || c == '_':
+			start := i
+			for i < len(expr) && (unicode.IsLetter(rune(expr[i])) ||
+				unicode.IsDigit(rune(expr[i])) || expr[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: expr[start:i], pos: start + 1})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(expr[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, errorf(i+1, "unexpected character %q", c)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: op, pos: i + 1})
+			i += len(op)
+		}
+	}
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(expr) + 1})
+	return tokens, nil
+}
+
+// lexString reads a double-quoted string starting at expr[start]. Backslash
+// escapes the following character. It returns the token and its length.
+func lexString(expr string, start int) (token, int, error) {
+	var b strings.Builder
+	i := start + 1
+	for i < len(expr) {
+		switch expr[i] {
+		case '\\':
+			if i+1 >= len(expr) {
+				return token{}, 0, errorf(i+1, "unterminated escape sequence")
+			}
+			b.WriteByte(expr[i+1])
+			i += 2
+		case '"':
+			return token{
+				kind:  tokenString,
+				text:  expr[start : i+1],
+				value: b.String(),
+				pos:   start + 1,
+			}, i + 1 - start, nil
+		default:
+			b.WriteByte(expr[i])
+			i++
+		}
+	}
+	return token{}, 0, errorf(start+1, "unterminated string")
+}
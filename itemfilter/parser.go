@@ -0,0 +1,208 @@
+package itemfilter
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/willroberts/poeapi"
+)
+
+// parser is a recursive descent parser for filter expressions:
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field operator value | modfield "has" string [ operator number ]
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword reports whether the next token is the given keyword, and consumes
+// it if so.
+func (p *parser) keyword(word string) bool {
+	t := p.peek()
+	if t.kind == tokenIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.keyword("not") {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	}
+
+	if t := p.peek(); t.kind == tokenLParen {
+		p.next()
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if t := p.next(); t.kind != tokenRParen {
+			return nil, errorf(t.pos, "expected ')', found %s", describe(t))
+		}
+		return n, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (node, error) {
+	t := p.next()
+	if t.kind != tokenIdent {
+		return nil, errorf(t.pos, "expected field name, found %s", describe(t))
+	}
+	name := strings.ToLower(t.text)
+
+	if field, ok := modFields[name]; ok {
+		return p.parseMod(field)
+	}
+
+	op := p.next()
+	if op.kind != tokenOperator {
+		return nil, errorf(op.pos, "expected operator after %q, found %s",
+			t.text, describe(op))
+	}
+	value := p.next()
+
+	if field, ok := stringFields[name]; ok {
+		if op.text != "=" && op.text != "!=" && op.text != "~" && op.text != "!~" {
+			return nil, errorf(op.pos, "operator %q is not valid for string field %q",
+				op.text, t.text)
+		}
+		if value.kind != tokenString {
+			return nil, errorf(value.pos, "expected string, found %s", describe(value))
+		}
+		s := value.value
+		if op.text == "~" || op.text == "!~" {
+			s = strings.ToLower(s)
+		}
+		return stringNode{field: field, op: op.text, value: s}, nil
+	}
+
+	if field, ok := numberFields[name]; ok {
+		if op.text == "~" || op.text == "!~" {
+			return nil, errorf(op.pos, "operator %q is not valid for numeric field %q",
+				op.text, t.text)
+		}
+		n, err := parseNumber(value)
+		if err != nil {
+			return nil, err
+		}
+		return numberNode{field: field, op: op.text, value: n}, nil
+	}
+
+	if field, ok := boolFields[name]; ok {
+		if op.text != "=" && op.text != "!=" {
+			return nil, errorf(op.pos, "operator %q is not valid for boolean field %q",
+				op.text, t.text)
+		}
+		var b bool
+		switch {
+		case value.kind == tokenIdent && strings.EqualFold(value.text, "true"):
+			b = true
+		case value.kind == tokenIdent && strings.EqualFold(value.text, "false"):
+			b = false
+		default:
+			return nil, errorf(value.pos, "expected true or false, found %s",
+				describe(value))
+		}
+		if op.text == "!=" {
+			b = !b
+		}
+		return boolNode{field: field, value: b}, nil
+	}
+
+	return nil, errorf(t.pos, "unknown field %q", t.text)
+}
+
+func (p *parser) parseMod(field func(poeapi.Item) []string) (node, error) {
+	if t := p.peek(); !p.keyword("has") {
+		return nil, errorf(t.pos, "expected \"has\", found %s", describe(t))
+	}
+	t := p.next()
+	if t.kind != tokenString {
+		return nil, errorf(t.pos, "expected mod template, found %s", describe(t))
+	}
+	n := modNode{field: field, template: t.value}
+
+	if op := p.peek(); op.kind == tokenOperator {
+		p.next()
+		if op.text == "~" || op.text == "!~" {
+			return nil, errorf(op.pos, "operator %q is not valid for mod values",
+				op.text)
+		}
+		v, err := parseNumber(p.next())
+		if err != nil {
+			return nil, err
+		}
+		n.op = op.text
+		n.value = v
+	}
+	return n, nil
+}
+
+func parseNumber(t token) (float64, error) {
+	if t.kind != tokenNumber {
+		return 0, errorf(t.pos, "expected number, found %s", describe(t))
+	}
+	n, err := strconv.ParseFloat(t.text, 64)
+	if err != nil {
+		return 0, errorf(t.pos, "invalid number %q", t.text)
+	}
+	return n, nil
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return t.kind.String()
+	}
+	return t.kind.String() + " " + strconv.Quote(t.text)
+}
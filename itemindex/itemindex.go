@@ -0,0 +1,518 @@
+// Package itemindex stores items from the public stash API in an embedded,
+// on-disk index which can be searched without re-reading the stash stream,
+// like a small self-hosted trade site. For example:
+//
+//	idx, err := itemindex.Open("items.db")
+//	if err != nil {
+//	    // Handle error.
+//	}
+//	defer idx.Close()
+//
+//	for batch := range river.Batches() {
+//	    if err := idx.Update(batch.Stashes...); err != nil {
+//	        // Handle error.
+//	    }
+//	    if err := river.Commit(batch); err != nil {
+//	        // Handle error.
+//	    }
+//	}
+//
+//	results, err := idx.Search(itemindex.Query{
+//	    League: "Standard",
+//	    Mods:   []itemindex.ModQuery{{Template: "+# to maximum Life", Min: 100}},
+//	})
+//
+// Items are written to an append-only log file, and the index is rebuilt in
+// memory from the log when it is opened. Only the fields needed for searching
+// are held in memory; matching items are read from disk. Items are removed
+// when they disappear from their stash or the stash is made private, and
+// Compact reclaims the space they used.
+package itemindex
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/willroberts/poeapi"
+	"github.com/willroberts/poeapi/mods"
+)
+
+var (
+	// ErrClosed is raised when using an index after it has been closed.
+	ErrClosed = errors.New("index is closed")
+
+	// ErrNotFound is raised when an item is not in the index.
+	ErrNotFound = errors.New("item not found")
+
+	// ErrCorrupt is raised when the log file contains a malformed record
+	// before its final line.
+	ErrCorrupt = errors.New("corrupt index file")
+)
+
+// Log record operations.
+const (
+	opPut    = "put"
+	opDelete = "delete"
+)
+
+// record is a single line of the log file.
+type record struct {
+	Op          string       `json:"op"`
+	ID          string       `json:"id"`
+	StashID     string       `json:"stash,omitempty"`
+	AccountName string       `json:"account,omitempty"`
+	League      string       `json:"league,omitempty"`
+	Item        *poeapi.Item `json:"item,omitempty"`
+}
+
+// entry is the in-memory state of an indexed item.
+type entry struct {
+	offset int64
+	size   int64
+	hash   uint64
+
+	stashID   string
+	terms     map[field]string
+	itemLevel int64
+	mods      map[string][]float64
+}
+
+// field identifies an inverted index.
+type field int
+
+const (
+	fieldName field = iota
+	fieldBaseType
+	fieldLeague
+	fieldAccount
+)
+
+// Index is an on-disk index of items. It is safe for concurrent use.
+type Index struct {
+	path   string
+	file   *os.File
+	writer *bufio.Writer
+	size   int64
+	live   int64
+
+	entries  map[string]*entry
+	stashes  map[string]map[string]struct{}
+	postings map[field]map[string]map[string]struct{}
+	mods     map[string]map[string]struct{}
+
+	closed bool
+	lock   sync.RWMutex
+}
+
+// Result is an item found by Search, along with the stash it is listed in.
+type Result struct {
+	Item        poeapi.Item
+	StashID     string
+	AccountName string
+	League      string
+}
+
+// Open opens the index stored at path, creating it if it does not exist. If
+// the final record of the log was only partly written, for example due to a
+// crash, it is discarded.
+func Open(path string) (*Index, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	idx := newIndex(path, f)
+	if err := idx.replay(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return idx, nil
+}
+
+func newIndex(path string, f *os.File) *Index {
+	return &Index{
+		path:    path,
+		file:    f,
+		writer:  bufio.NewWriter(f),
+		entries: make(map[string]*entry),
+		stashes: make(map[string]map[string]struct{}),
+		postings: map[field]map[string]map[string]struct{}{
+			fieldName:     make(map[string]map[string]struct{}),
+			fieldBaseType: make(map[string]map[string]struct{}),
+			fieldLeague:   make(map[string]map[string]struct{}),
+			fieldAccount:  make(map[string]map[string]struct{}),
+		},
+		mods: make(map[string]map[string]struct{}),
+	}
+}
+
+// replay rebuilds the in-memory index from the log file.
+func (idx *Index) replay() error {
+	if _, err := idx.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	r := bufio.NewReader(idx.file)
+	var offset int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if err == io.EOF {
+			// Discard a partial final record.
+			if err := idx.file.Truncate(offset); err != nil {
+				return err
+			}
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		var rec record
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return ErrCorrupt
+		}
+		idx.apply(rec, offset, line)
+		offset += int64(len(line))
+	}
+	idx.size = offset
+	_, err := idx.file.Seek(offset, io.SeekStart)
+	return err
+}
+
+// Close flushes pending writes and closes the log file.
+func (idx *Index) Close() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.closed {
+		return ErrClosed
+	}
+	idx.closed = true
+	if err := idx.writer.Flush(); err != nil {
+		idx.file.Close()
+		return err
+	}
+	if err := idx.file.Sync(); err != nil {
+		idx.file.Close()
+		return err
+	}
+	return idx.file.Close()
+}
+
+// Len returns the number of items in the index.
+func (idx *Index) Len() int {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+	return len(idx.entries)
+}
+
+// Update indexes the items in each stash. Items which were previously indexed
+// in a stash but are no longer in it are removed, as are all items in private
+// stashes. Unchanged items are not rewritten.
+func (idx *Index) Update(stashes ...poeapi.Stash) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.closed {
+		return ErrClosed
+	}
+	for _, s := range stashes {
+		if err := idx.updateStash(s); err != nil {
+			return err
+		}
+	}
+	return idx.writer.Flush()
+}
+
+func (idx *Index) updateStash(s poeapi.Stash) error {
+	seen := make(map[string]struct{}, len(s.Items))
+	if s.Public {
+		for n := range s.Items {
+			item := s.Items[n]
+			seen[item.ID] = struct{}{}
+			league := item.League
+			if league == "" {
+				league = s.League
+			}
+			err := idx.write(record{
+				Op:          opPut,
+				ID:          item.ID,
+				StashID:     s.ID,
+				AccountName: s.AccountName,
+				League:      league,
+				Item:        &item,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	var removed []string
+	for id := range idx.stashes[s.ID] {
+		if _, ok := seen[id]; !ok {
+			removed = append(removed, id)
+		}
+	}
+	for _, id := range removed {
+		if err := idx.write(record{Op: opDelete, ID: id}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete removes an item from the index.
+func (idx *Index) Delete(id string) error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.closed {
+		return ErrClosed
+	}
+	if _, ok := idx.entries[id]; !ok {
+		return ErrNotFound
+	}
+	if err := idx.write(record{Op: opDelete, ID: id}); err != nil {
+		return err
+	}
+	return idx.writer.Flush()
+}
+
+// write appends a record to the log and applies it to the index. Records
+// which would not change an indexed item are skipped.
+func (idx *Index) write(rec record) error {
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if rec.Op == opPut {
+		if e, ok := idx.entries[rec.ID]; ok && e.hash == hashRecord(b) {
+			return nil
+		}
+	}
+	if _, err := idx.writer.Write(b); err != nil {
+		return err
+	}
+	idx.apply(rec, idx.size, b)
+	idx.size += int64(len(b))
+	return nil
+}
+
+// apply updates the in-memory index with a record stored at the given offset.
+func (idx *Index) apply(rec record, offset int64, b []byte) {
+	idx.remove(rec.ID)
+	if rec.Op != opPut || rec.Item == nil {
+		return
+	}
+
+	e := &entry{
+		offset:    offset,
+		size:      int64(len(b)),
+		hash:      hashRecord(b),
+		stashID:   rec.StashID,
+		itemLevel: rec.Item.ItemLevel,
+		terms: map[field]string{
+			fieldName:     normalize(rec.Item.Name),
+			fieldBaseType: normalize(baseType(*rec.Item)),
+			fieldLeague:   normalize(rec.League),
+			fieldAccount:  normalize(rec.AccountName),
+		},
+		mods: make(map[string][]float64),
+	}
+	for _, mod := range mods.ItemMods(*rec.Item) {
+		template := normalize(mod.Template)
+		e.mods[template] = append(e.mods[template], mod.Value())
+	}
+
+	idx.entries[rec.ID] = e
+	idx.live += e.size
+	addPosting(idx.stashes, e.stashID, rec.ID)
+	for f, term := range e.terms {
+		if term != "" {
+			addPosting(idx.postings[f], term, rec.ID)
+		}
+	}
+	for template := range e.mods {
+		addPosting(idx.mods, template, rec.ID)
+	}
+}
+
+// remove deletes an item from the in-memory index.
+func (idx *Index) remove(id string) {
+	e, ok := idx.entries[id]
+	if !ok {
+		return
+	}
+	delete(idx.entries, id)
+	idx.live -= e.size
+	removePosting(idx.stashes, e.stashID, id)
+	for f, term := range e.terms {
+		removePosting(idx.postings[f], term, id)
+	}
+	for template := range e.mods {
+		removePosting(idx.mods, template, id)
+	}
+}
+
+// Get returns the indexed item with the given ID.
+func (idx *Index) Get(id string) (Result, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx.closed {
+		return Result{}, ErrClosed
+	}
+	e, ok := idx.entries[id]
+	if !ok {
+		return Result{}, ErrNotFound
+	}
+	return idx.read(e)
+}
+
+// read loads an item's record from the log file.
+func (idx *Index) read(e *entry) (Result, error) {
+	b := make([]byte, e.size)
+	if _, err := idx.file.ReadAt(b, e.offset); err != nil {
+		return Result{}, err
+	}
+	var rec record
+	if err := json.Unmarshal(b, &rec); err != nil || rec.Item == nil {
+		return Result{}, ErrCorrupt
+	}
+	return Result{
+		Item:        *rec.Item,
+		StashID:     rec.StashID,
+		AccountName: rec.AccountName,
+		League:      rec.League,
+	}, nil
+}
+
+// Garbage returns the fraction of the log file occupied by removed or
+// replaced items, which Compact would reclaim.
+func (idx *Index) Garbage() float64 {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx.size == 0 {
+		return 0
+	}
+	return float64(idx.size-idx.live) / float64(idx.size)
+}
+
+// Compact rewrites the log file so that it only contains indexed items. The
+// new file is written alongside the old one and renamed into place, so a
+// crash during compaction leaves the original intact.
+func (idx *Index) Compact() error {
+	idx.lock.Lock()
+	defer idx.lock.Unlock()
+
+	if idx.closed {
+		return ErrClosed
+	}
+	if err := idx.writer.Flush(); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(idx.path), filepath.Base(idx.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w := bufio.NewWriter(tmp)
+	offsets := make(map[string]int64, len(idx.entries))
+	var offset int64
+	for id, e := range idx.entries {
+		b := make([]byte, e.size)
+		if _, err := idx.file.ReadAt(b, e.offset); err != nil {
+			tmp.Close()
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			tmp.Close()
+			return err
+		}
+		offsets[id] = offset
+		offset += e.size
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	// Temporary files are created with mode 0600, so restore the index's
+	// permissions before replacing it.
+	info, err := idx.file.Stat()
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(info.Mode().Perm()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := os.Rename(tmp.Name(), idx.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	idx.file.Close()
+	idx.file = tmp
+	idx.writer = bufio.NewWriter(tmp)
+	idx.size = offset
+	idx.live = offset
+	for id, e := range idx.entries {
+		e.offset = offsets[id]
+	}
+	return nil
+}
+
+func hashRecord(b []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(b)
+	return h.Sum64()
+}
+
+// baseType returns the item's base type, falling back to its type line for
+// listings which predate the baseType field.
+func baseType(item poeapi.Item) string {
+	if item.BaseType != "" {
+		return item.BaseType
+	}
+	return item.TypeLine
+}
+
+func normalize(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+func addPosting(index map[string]map[string]struct{}, term, id string) {
+	ids, ok := index[term]
+	if !ok {
+		ids = make(map[string]struct{})
+		index[term] = ids
+	}
+	ids[id] = struct{}{}
+}
+
+func removePosting(index map[string]map[string]struct{}, term, id string) {
+	ids, ok := index[term]
+	if !ok {
+		return
+	}
+	delete(ids, id)
+	if len(ids) == 0 {
+		delete(index, term)
+	}
+}
@@ -0,0 +1,257 @@
+package itemindex
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/willroberts/poeapi"
+)
+
+func openTestIndex(t *testing.T) (*Index, string) {
+	path := filepath.Join(t.TempDir(), "items.db")
+	idx, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open index: %v", err)
+	}
+	return idx, path
+}
+
+func testStash(id string, items ...poeapi.Item) poeapi.Stash {
+	return poeapi.Stash{
+		ID:          id,
+		AccountName: "Account1",
+		League:      "Standard",
+		Public:      true,
+		Items:       items,
+	}
+}
+
+var (
+	kaom = poeapi.Item{
+		ID:           "kaom",
+		Name:         "Kaom's Heart",
+		TypeLine:     "Glorious Plate",
+		ItemLevel:    84,
+		ExplicitMods: []string{"+500 to maximum Life", "Has no Sockets"},
+	}
+	ring = poeapi.Item{
+		ID:           "ring",
+		TypeLine:     "Two-Stone Ring",
+		BaseType:     "Two-Stone Ring",
+		ItemLevel:    75,
+		ImplicitMods: []string{"+14% to Cold and Lightning Resistances"},
+		ExplicitMods: []string{"+70 to maximum Life", "Adds 10 to 20 Fire Damage to Attacks"},
+	}
+	belt = poeapi.Item{
+		ID:           "belt",
+		TypeLine:     "Leather Belt",
+		ItemLevel:    60,
+		ImplicitMods: []string{"+30 to maximum Life"},
+	}
+)
+
+func searchIDs(t *testing.T, idx *Index, q Query) []string {
+	results, err := idx.Search(q)
+	if err != nil {
+		t.Fatalf("failed to search index: %v", err)
+	}
+	ids := make([]string, 0, len(results))
+	for _, r := range results {
+		ids = append(ids, r.Item.ID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	idx, _ := openTestIndex(t)
+	defer idx.Close()
+
+	if err := idx.Update(testStash("stash1", kaom, ring), testStash("stash2", belt)); err != nil {
+		t.Fatalf("failed to update index: %v", err)
+	}
+
+	cases := []struct {
+		query    Query
+		expected string
+	}{
+		{Query{}, "belt,kaom,ring"},
+		{Query{Name: "kaom's heart"}, "kaom"},
+		{Query{BaseType: "Two-Stone Ring"}, "ring"},
+		{Query{BaseType: "Glorious Plate", League: "Standard"}, "kaom"},
+		{Query{League: "Hardcore"}, ""},
+		{Query{AccountName: "account1", Limit: 2}, "belt,kaom"},
+		{Query{MinItemLevel: 70}, "kaom,ring"},
+		{Query{MinItemLevel: 70, MaxItemLevel: 80}, "ring"},
+		{Query{Mods: []ModQuery{{Template: "+# to maximum Life"}}}, "belt,kaom,ring"},
+		{Query{Mods: []ModQuery{{Template: "+# to maximum Life", Min: 50, Max: 100}}}, "ring"},
+		{Query{Mods: []ModQuery{{Template: "adds # to # fire damage to attacks", Min: 15}}}, "ring"},
+		{Query{Mods: []ModQuery{{Template: "adds # to # fire damage to attacks", Min: 16}}}, ""},
+		{Query{Mods: []ModQuery{
+			{Template: "+# to maximum Life"},
+			{Template: "Has no Sockets"},
+		}}, "kaom"},
+	}
+	for _, c := range cases {
+		if ids := strings.Join(searchIDs(t, idx, c.query), ","); ids != c.expected {
+			t.Fatalf("unexpected results for %+v. expected %q, got %q", c.query, c.expected, ids)
+		}
+	}
+
+	n, err := idx.Count(Query{AccountName: "Account1", Limit: 1})
+	if err != nil || n != 3 {
+		t.Fatalf("failed to count results. expected 3, got %d (%v)", n, err)
+	}
+
+	r, err := idx.Get("ring")
+	if err != nil {
+		t.Fatalf("failed to get item: %v", err)
+	}
+	if r.StashID != "stash1" || r.League != "Standard" || len(r.Item.ExplicitMods) != 2 {
+		t.Fatalf("unexpected item: %+v", r)
+	}
+	if _, err := idx.Get("missing"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("failed to detect missing item: %v", err)
+	}
+}
+
+func TestEviction(t *testing.T) {
+	idx, _ := openTestIndex(t)
+	defer idx.Close()
+
+	idx.Update(testStash("stash1", kaom, ring), testStash("stash2", belt))
+
+	// The ring is sold, and the belt's stash is made private.
+	if err := idx.Update(testStash("stash1", kaom), poeapi.Stash{ID: "stash2"}); err != nil {
+		t.Fatalf("failed to update index: %v", err)
+	}
+	if ids := strings.Join(searchIDs(t, idx, Query{}), ","); ids != "kaom" {
+		t.Fatalf("failed to evict delisted items. got %q", ids)
+	}
+	if ids := searchIDs(t, idx, Query{Mods: []ModQuery{{Template: "+# to maximum Life", Max: 100}}}); len(ids) != 0 {
+		t.Fatalf("failed to remove delisted items from mod index: %v", ids)
+	}
+
+	// Moving an item to another stash removes it from the first.
+	moved := kaom
+	moved.Note = "~b/o 1 divine"
+	idx.Update(testStash("stash3", moved))
+	idx.Update(testStash("stash1"))
+	r, err := idx.Get("kaom")
+	if err != nil || r.StashID != "stash3" || r.Item.Note != "~b/o 1 divine" {
+		t.Fatalf("failed to move item between stashes: %+v (%v)", r, err)
+	}
+
+	if err := idx.Delete("kaom"); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	if err := idx.Delete("kaom"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("failed to detect missing item: %v", err)
+	}
+	if idx.Len() != 0 {
+		t.Fatalf("unexpected items in index: %d", idx.Len())
+	}
+}
+
+func TestReopen(t *testing.T) {
+	idx, path := openTestIndex(t)
+	idx.Update(testStash("stash1", kaom, ring), testStash("stash2", belt))
+	idx.Update(testStash("stash1", ring))
+	if err := idx.Close(); err != nil {
+		t.Fatalf("failed to close index: %v", err)
+	}
+	if err := idx.Close(); !errors.Is(err, ErrClosed) {
+		t.Fatalf("failed to detect closed index: %v", err)
+	}
+	if _, err := idx.Search(Query{}); !errors.Is(err, ErrClosed) {
+		t.Fatalf("failed to detect closed index: %v", err)
+	}
+
+	// Simulate a crash while writing a record.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("failed to open index file: %v", err)
+	}
+	f.WriteString(`{"op":"put","id":"partial"`)
+	f.Close()
+
+	idx, err = Open(path)
+	if err != nil {
+		t.Fatalf("failed to reopen index: %v", err)
+	}
+	defer idx.Close()
+	if ids := strings.Join(searchIDs(t, idx, Query{}), ","); ids != "belt,ring" {
+		t.Fatalf("failed to restore index. got %q", ids)
+	}
+	if ids := searchIDs(t, idx, Query{BaseType: "two-stone ring"}); len(ids) != 1 {
+		t.Fatalf("failed to rebuild inverted index: %v", ids)
+	}
+
+	// Writes continue after the discarded record.
+	idx.Update(testStash("stash3", kaom))
+	if _, err := idx.Get("kaom"); err != nil {
+		t.Fatalf("failed to write after reopening: %v", err)
+	}
+}
+
+func TestOpenCorruptIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.db")
+	if err := ioutil.WriteFile(path, []byte("not json\n"), 0644); err != nil {
+		t.Fatalf("failed to write index file: %v", err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("failed to detect corrupt index: %v", err)
+	}
+}
+
+func TestCompact(t *testing.T) {
+	idx, path := openTestIndex(t)
+	defer idx.Close()
+
+	for i := 0; i < 5; i++ {
+		r := ring
+		r.ItemLevel = int64(70 + i)
+		idx.Update(testStash("stash1", kaom, r))
+	}
+	idx.Update(testStash("stash2", belt))
+	if g := idx.Garbage(); g <= 0.5 {
+		t.Fatalf("expected replaced items to leave garbage, got %v", g)
+	}
+
+	before, _ := os.Stat(path)
+	if err := idx.Compact(); err != nil {
+		t.Fatalf("failed to compact index: %v", err)
+	}
+	after, _ := os.Stat(path)
+	if after.Size() >= before.Size() || idx.Garbage() != 0 {
+		t.Fatalf("failed to reclaim space: %d -> %d bytes", before.Size(), after.Size())
+	}
+	if after.Mode() != before.Mode() {
+		t.Fatalf("failed to preserve file mode: %v -> %v", before.Mode(), after.Mode())
+	}
+
+	r, err := idx.Get("ring")
+	if err != nil || r.Item.ItemLevel != 74 {
+		t.Fatalf("failed to read item after compaction: %+v (%v)", r, err)
+	}
+	idx.Update(testStash("stash2"))
+	if ids := strings.Join(searchIDs(t, idx, Query{}), ","); ids != "kaom,ring" {
+		t.Fatalf("failed to update index after compaction. got %q", ids)
+	}
+}
+
+func TestUnchangedItemsAreNotRewritten(t *testing.T) {
+	idx, path := openTestIndex(t)
+	defer idx.Close()
+
+	idx.Update(testStash("stash1", kaom, ring))
+	before, _ := os.Stat(path)
+	idx.Update(testStash("stash1", kaom, ring))
+	after, _ := os.Stat(path)
+	if after.Size() != before.Size() {
+		t.Fatalf("unchanged items were rewritten: %d -> %d bytes", before.Size(), after.Size())
+	}
+}
@@ -0,0 +1,157 @@
+package itemindex
+
+import (
+	"sort"
+)
+
+// Query describes the items to find with Search. String fields are matched
+// exactly, ignoring case, and empty fields match any item. All conditions must
+// match.
+type Query struct {
+	Name        string
+	BaseType    string
+	League      string
+	AccountName string
+
+	// Item level bounds. Zero means unbounded.
+	MinItemLevel int64
+	MaxItemLevel int64
+
+	Mods []ModQuery
+
+	// The maximum number of results. Zero means no limit.
+	Limit int
+}
+
+// ModQuery matches items with a mod, identified by its template such as
+// "+# to maximum Life". Mods with several numbers, such as
+// "Adds # to # Fire Damage", are compared by their average.
+type ModQuery struct {
+	Template string
+
+	// Value bounds. Since mod values are never negative, zero means
+	// unbounded.
+	Min float64
+	Max float64
+}
+
+// Search returns the items matching the query, ordered by item ID.
+func (idx *Index) Search(q Query) ([]Result, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx.closed {
+		return nil, ErrClosed
+	}
+
+	ids := idx.match(q)
+	sort.Strings(ids)
+	if q.Limit > 0 && len(ids) > q.Limit {
+		ids = ids[:q.Limit]
+	}
+
+	results := make([]Result, 0, len(ids))
+	for _, id := range ids {
+		r, err := idx.read(idx.entries[id])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, nil
+}
+
+// Count returns the number of items matching the query, ignoring its limit.
+func (idx *Index) Count(q Query) (int, error) {
+	idx.lock.RLock()
+	defer idx.lock.RUnlock()
+
+	if idx.closed {
+		return 0, ErrClosed
+	}
+	return len(idx.match(q)), nil
+}
+
+// match returns the IDs of the items matching the query. Candidates are
+// taken from the smallest inverted index named in the query, and then checked
+// against every condition.
+func (idx *Index) match(q Query) []string {
+	terms := map[field]string{
+		fieldName:     normalize(q.Name),
+		fieldBaseType: normalize(q.BaseType),
+		fieldLeague:   normalize(q.League),
+		fieldAccount:  normalize(q.AccountName),
+	}
+
+	var candidates map[string]struct{}
+	narrow := func(ids map[string]struct{}) {
+		if candidates == nil || len(ids) < len(candidates) {
+			candidates = ids
+		}
+	}
+	for f, term := range terms {
+		if term != "" {
+			narrow(nonNil(idx.postings[f][term]))
+		}
+	}
+	for _, m := range q.Mods {
+		narrow(nonNil(idx.mods[normalize(m.Template)]))
+	}
+
+	var ids []string
+	check := func(id string, e *entry) {
+		if matches(e, q, terms) {
+			ids = append(ids, id)
+		}
+	}
+	if candidates == nil {
+		for id, e := range idx.entries {
+			check(id, e)
+		}
+		return ids
+	}
+	for id := range candidates {
+		check(id, idx.entries[id])
+	}
+	return ids
+}
+
+func matches(e *entry, q Query, terms map[field]string) bool {
+	for f, term := range terms {
+		if term != "" && e.terms[f] != term {
+			return false
+		}
+	}
+	if q.MinItemLevel > 0 && e.itemLevel < q.MinItemLevel {
+		return false
+	}
+	if q.MaxItemLevel > 0 && e.itemLevel > q.MaxItemLevel {
+		return false
+	}
+	for _, m := range q.Mods {
+		if !matchesMod(e.mods[normalize(m.Template)], m) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesMod reports whether any of an item's values for a mod are within the
+// query's bounds.
+func matchesMod(values []float64, m ModQuery) bool {
+	for _, v := range values {
+		if (m.Min == 0 || v >= m.Min) && (m.Max == 0 || v <= m.Max) {
+			return true
+		}
+	}
+	return false
+}
+
+var empty = map[string]struct{}{}
+
+func nonNil(ids map[string]struct{}) map[string]struct{} {
+	if ids == nil {
+		return empty
+	}
+	return ids
+}
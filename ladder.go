@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -120,11 +121,15 @@ func validateGetLabyrinthLadderOptions(opts GetLadderOptions) error {
 }
 
 func (c *client) GetLadder(opts GetLadderOptions) (Ladder, error) {
+	return c.GetLadderContext(context.Background(), opts)
+}
+
+func (c *client) GetLadderContext(ctx context.Context, opts GetLadderOptions) (Ladder, error) {
 	entries := make([]LadderEntry, 0)
 	opts.limit = maxLadderLimit
 
 	// Make one initial request to determine the size of the ladder.
-	first, err := c.getLadderPage(opts)
+	first, err := c.getLadderPage(ctx, opts)
 	if err != nil {
 		return Ladder{}, err
 	}
@@ -133,7 +138,10 @@ func (c *client) GetLadder(opts GetLadderOptions) (Ladder, error) {
 		return first, nil
 	}
 
-	// If there are entries remaining, make further requests.
+	// If there are entries remaining, make further requests. The first
+	// failure cancels any page requests which are still pending.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 	var (
 		wg    sync.WaitGroup
 		lock  sync.RWMutex
@@ -145,9 +153,10 @@ func (c *client) GetLadder(opts GetLadderOptions) (Ladder, error) {
 			subOpts := opts
 			subOpts.offset = offset
 
-			page, err := c.getLadderPage(subOpts)
+			page, err := c.getLadderPage(ctx, subOpts)
 			if err != nil {
 				errCh <- err
+				cancel()
 			}
 
 			lock.Lock()
@@ -173,13 +182,13 @@ func (c *client) GetLadder(opts GetLadderOptions) (Ladder, error) {
 	return ladder, nil
 }
 
-func (c *client) getLadderPage(opts GetLadderOptions) (Ladder, error) {
+func (c *client) getLadderPage(ctx context.Context, opts GetLadderOptions) (Ladder, error) {
 	if err := validateGetLadderOptions(opts); err != nil {
 		return Ladder{}, err
 	}
 	url := fmt.Sprintf("%s/%s?%s", c.formatURL(laddersEndpoint), opts.ID,
 		opts.toQueryParams())
-	resp, err := c.get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return Ladder{}, err
 	}
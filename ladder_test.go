@@ -1,6 +1,10 @@
 package poeapi
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+)
 
 func TestLadderOptionstoQueryParams(t *testing.T) {
 	var (
@@ -177,7 +181,7 @@ func TestGetLadderPage(t *testing.T) {
 		limit:               200,
 	}
 
-	_, err := c.getLadderPage(opts)
+	_, err := c.getLadderPage(context.Background(), opts)
 	if err != nil {
 		t.Fatalf("failed to get ladder page: %v", err)
 	}
@@ -191,11 +195,11 @@ func TestGetLadderPageFailure(t *testing.T) {
 		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
 		httpClient: testClient,
 	}
-	_, err := c.getLadderPage(GetLadderOptions{
+	_, err := c.getLadderPage(context.Background(), GetLadderOptions{
 		ID:    "Nonexistent",
 		limit: 1,
 	})
-	if err != ErrNotFound {
+	if !errors.Is(err, ErrNotFound) {
 		t.Fatal("failed to detect ladder retrieval failure")
 	}
 }
@@ -212,7 +216,7 @@ func TestGetLadderRequestFailure(t *testing.T) {
 		ID: "test",
 	}
 	_, err := c.GetLadder(opts)
-	if err != ErrNotFound {
+	if !errors.Is(err, ErrNotFound) {
 		t.Fatal("failed to detect ladder request failure")
 	}
 }
@@ -229,8 +233,8 @@ func TestGetLadderPageRequestFailure(t *testing.T) {
 		ID:    "test",
 		limit: 200,
 	}
-	_, err := c.getLadderPage(opts)
-	if err != ErrNotFound {
+	_, err := c.getLadderPage(context.Background(), opts)
+	if !errors.Is(err, ErrNotFound) {
 		t.Fatal("failed to detect ladder request failure")
 	}
 }
@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -40,10 +41,14 @@ func validateGetLeagueOptions(opts GetLeagueOptions) error {
 }
 
 func (c *client) GetLeague(opts GetLeagueOptions) (League, error) {
+	return c.GetLeagueContext(context.Background(), opts)
+}
+
+func (c *client) GetLeagueContext(ctx context.Context, opts GetLeagueOptions) (League, error) {
 	if err := validateGetLeagueOptions(opts); err != nil {
 		return League{}, err
 	}
-	resp, err := c.get(fmt.Sprintf("%s/%s", c.formatURL(leaguesEndpoint), opts.ID))
+	resp, err := c.get(ctx, fmt.Sprintf("%s/%s", c.formatURL(leaguesEndpoint), opts.ID))
 	if err != nil {
 		return League{}, err
 	}
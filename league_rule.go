@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 )
@@ -21,12 +22,18 @@ func validateLeagueRuleOptions(opts GetLeagueRuleOptions) error {
 
 // GetLeagueRule retrieves a single league rule from the API.
 func (c *client) GetLeagueRule(opts GetLeagueRuleOptions) (LeagueRule, error) {
+	return c.GetLeagueRuleContext(context.Background(), opts)
+}
+
+// GetLeagueRuleContext retrieves a single league rule from the API using the
+// provided context.
+func (c *client) GetLeagueRuleContext(ctx context.Context, opts GetLeagueRuleOptions) (LeagueRule, error) {
 	if err := validateLeagueRuleOptions(opts); err != nil {
 		return LeagueRule{}, err
 	}
 
 	url := fmt.Sprintf("%s/%s", c.formatURL(leagueRulesEndpoint), opts.ID)
-	resp, err := c.get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return LeagueRule{}, err
 	}
@@ -1,11 +1,16 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 )
 
 func (c *client) GetLeagueRules() ([]LeagueRule, error) {
-	resp, err := c.get(c.formatURL(leagueRulesEndpoint))
+	return c.GetLeagueRulesContext(context.Background())
+}
+
+func (c *client) GetLeagueRulesContext(ctx context.Context) ([]LeagueRule, error) {
+	resp, err := c.get(ctx, c.formatURL(leagueRulesEndpoint))
 	if err != nil {
 		return []LeagueRule{}, err
 	}
@@ -1,6 +1,10 @@
 package poeapi
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"testing"
+)
 
 func TestGetLeague(t *testing.T) {
 	c := client{
@@ -17,6 +21,23 @@ func TestGetLeague(t *testing.T) {
 	}
 }
 
+func TestGetLeagueContextCanceled(t *testing.T) {
+	c := client{
+		host:       testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.GetLeagueContext(ctx, GetLeagueOptions{ID: "Standard"})
+	if err != context.Canceled {
+		t.Fatalf("failed to detect canceled context in league request: %v", err)
+	}
+}
+
 func TestGetLeagueWithInvalidOptions(t *testing.T) {
 	c := client{
 		host:       testHost,
@@ -42,7 +63,7 @@ func TestGetLeagueWithRequestFailure(t *testing.T) {
 	}
 
 	_, err := c.GetLeague(GetLeagueOptions{ID: "Nonexistent"})
-	if err != ErrNotFound {
+	if !errors.Is(err, ErrNotFound) {
 		t.Fatal("failed to detect request error for league request")
 	}
 }
@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"net/url"
 	"strconv"
@@ -94,10 +95,14 @@ func validateGetLeaguesOptions(opts GetLeaguesOptions) error {
 }
 
 func (c *client) GetLeagues(opts GetLeaguesOptions) ([]League, error) {
+	return c.GetLeaguesContext(context.Background(), opts)
+}
+
+func (c *client) GetLeaguesContext(ctx context.Context, opts GetLeaguesOptions) ([]League, error) {
 	if err := validateGetLeaguesOptions(opts); err != nil {
 		return []League{}, err
 	}
-	resp, err := c.get(c.formatURL(leaguesEndpoint))
+	resp, err := c.get(ctx, c.formatURL(leaguesEndpoint))
 	if err != nil {
 		return []League{}, err
 	}
@@ -0,0 +1,29 @@
+package poeapi
+
+import "net/http"
+
+// Middleware wraps an http.RoundTripper to observe or modify requests and
+// responses, such as for logging, header injection, or tracing. Middleware
+// must be safe for concurrent use.
+type Middleware func(http.RoundTripper) http.RoundTripper
+
+// RoundTripperFunc adapts an ordinary function to the http.RoundTripper
+// interface, which is convenient when writing Middleware.
+type RoundTripperFunc func(*http.Request) (*http.Response, error)
+
+// RoundTrip calls f(req).
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// chainMiddleware wraps base with each middleware so that middleware[0] is the
+// outermost. A nil base uses http.DefaultTransport.
+func chainMiddleware(base http.RoundTripper, middleware []Middleware) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		base = middleware[i](base)
+	}
+	return base
+}
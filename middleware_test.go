@@ -0,0 +1,108 @@
+package poeapi
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var (
+		order []string
+		lock  sync.Mutex
+		mw    = func(name string) Middleware {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					lock.Lock()
+					order = append(order, name)
+					lock.Unlock()
+					return next.RoundTrip(req)
+				})
+			}
+		}
+	)
+	opts := DefaultClientOptions
+	opts.Host = testHost
+	opts.UseSSL = false
+	opts.UseCache = false
+	opts.UseDNSCache = false
+	opts.RateLimit = UnlimitedRate
+	opts.RequestTimeout = testTimeout
+	opts.Middleware = []Middleware{mw("first"), mw("second")}
+
+	c, err := NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := c.GetLeagueRules(); err != nil {
+		t.Fatalf("failed to get league rules through middleware: %v", err)
+	}
+	if strings.Join(order, ",") != "first,second" {
+		t.Fatalf("unexpected middleware order: %v", order)
+	}
+}
+
+func TestCustomTransport(t *testing.T) {
+	var userAgent string
+	transport := RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		userAgent = req.Header.Get("User-Agent")
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{},
+			Body:       ioutil.NopCloser(strings.NewReader("[]")),
+			Request:    req,
+		}, nil
+	})
+	setUserAgent := func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			req = req.Clone(req.Context())
+			req.Header.Set("User-Agent", "poeapi-test")
+			return next.RoundTrip(req)
+		})
+	}
+
+	opts := DefaultClientOptions
+	opts.UseCache = false
+	opts.RateLimit = UnlimitedRate
+	opts.Transport = transport
+	opts.Middleware = []Middleware{setUserAgent}
+
+	c, err := NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := c.GetLeagueRules(); err != nil {
+		t.Fatalf("failed to get league rules with custom transport: %v", err)
+	}
+	if userAgent != "poeapi-test" {
+		t.Fatalf("failed to inject header: got %q", userAgent)
+	}
+}
+
+func TestCustomHTTPClientIsNotModified(t *testing.T) {
+	var (
+		httpClient = &http.Client{Timeout: testTimeout}
+		opts       = DefaultClientOptions
+	)
+	opts.HTTPClient = httpClient
+	opts.Middleware = []Middleware{
+		func(next http.RoundTripper) http.RoundTripper { return next },
+	}
+	if _, err := NewAPIClient(opts); err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if httpClient.Transport != nil {
+		t.Fatal("middleware modified the provided http client")
+	}
+}
+
+func TestValidateOptionsHTTPClientAndTransport(t *testing.T) {
+	opts := DefaultClientOptions
+	opts.HTTPClient = &http.Client{}
+	opts.Transport = http.DefaultTransport
+	if err := validateClientOptions(opts); err != ErrInvalidHTTPClient {
+		t.Fatal("failed to detect conflicting http client options")
+	}
+}
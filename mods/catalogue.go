@@ -0,0 +1,146 @@
+package mods
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/willroberts/poeapi"
+)
+
+// Stat is an entry in a Catalogue.
+type Stat struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Type Type   `json:"type"`
+}
+
+// Catalogue maps mod templates to stats.
+type Catalogue struct {
+	stats      map[string]Stat
+	byTemplate map[catalogueKey]Stat
+}
+
+type catalogueKey struct {
+	t        Type
+	template string
+}
+
+// catalogueFile is the format of the trade API's stat data, which is served
+// at /api/trade/data/stats.
+type catalogueFile struct {
+	Result []struct {
+		Label   string `json:"label"`
+		Entries []Stat `json:"entries"`
+	} `json:"result"`
+}
+
+// LoadCatalogue reads a catalogue from a JSON file in the format of the trade
+// API's stat data.
+func LoadCatalogue(path string) (*Catalogue, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadCatalogue(f)
+}
+
+// ReadCatalogue reads a catalogue in the format of the trade API's stat data.
+func ReadCatalogue(r io.Reader) (*Catalogue, error) {
+	var file catalogueFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+	var stats []Stat
+	for _, group := range file.Result {
+		stats = append(stats, group.Entries...)
+	}
+	return NewCatalogue(stats), nil
+}
+
+// NewCatalogue creates a catalogue of the given stats. When several stats of
+// the same type share a template, the first is used.
+func NewCatalogue(stats []Stat) *Catalogue {
+	c := &Catalogue{
+		stats:      make(map[string]Stat, len(stats)),
+		byTemplate: make(map[catalogueKey]Stat, len(stats)),
+	}
+	for _, s := range stats {
+		if s.Type == "" {
+			s.Type = Type(strings.SplitN(s.ID, ".", 2)[0])
+		}
+		c.stats[s.ID] = s
+		key := catalogueKey{s.Type, strings.ToLower(s.Text)}
+		if _, ok := c.byTemplate[key]; !ok {
+			c.byTemplate[key] = s
+		}
+	}
+	return c
+}
+
+// Len returns the number of stats in the catalogue.
+func (c *Catalogue) Len() int {
+	return len(c.stats)
+}
+
+// Stat returns the stat with the given ID.
+func (c *Catalogue) Stat(id string) (Stat, bool) {
+	s, ok := c.stats[id]
+	return s, ok
+}
+
+// Lookup returns the stat matching a mod's template and type. Negative values
+// are matched against the template for positive values, since the trade API
+// only lists the latter, e.g. "-10% to Fire Resistance" matches
+// "+#% to Fire Resistance".
+func (c *Catalogue) Lookup(mod Mod) (Stat, bool) {
+	template := strings.ToLower(mod.Template)
+	if s, ok := c.byTemplate[catalogueKey{mod.Type, template}]; ok {
+		return s, true
+	}
+	if strings.HasPrefix(template, "-") {
+		s, ok := c.byTemplate[catalogueKey{mod.Type, "+" + template[1:]}]
+		return s, ok
+	}
+	return Stat{}, false
+}
+
+// Parse parses a mod line of the given type and sets its stat ID from the
+// catalogue. A leading minus sign is applied to the first value when the mod
+// matches a stat for positive values.
+func (c *Catalogue) Parse(text string, t Type) Mod {
+	return c.resolve(Parse(text, t))
+}
+
+// ItemMods parses each of the item's mods, setting stat IDs from the
+// catalogue where the item does not include them.
+func (c *Catalogue) ItemMods(item poeapi.Item) Mods {
+	mods := ItemMods(item)
+	for n := range mods {
+		if mods[n].StatID == "" {
+			mods[n] = c.resolve(mods[n])
+		}
+	}
+	return mods
+}
+
+func (c *Catalogue) resolve(mod Mod) Mod {
+	s, ok := c.Lookup(mod)
+	if !ok {
+		return mod
+	}
+	mod.StatID = s.ID
+	mod.StatIDs = []string{s.ID}
+	if strings.EqualFold(s.Text, mod.Template) {
+		return mod
+	}
+	mod.Template = s.Text
+	if len(mod.Values) > 0 {
+		values := append([]float64{}, mod.Values...)
+		values[0] = -values[0]
+		mod.Values = values
+	}
+	return mod
+}
@@ -0,0 +1,193 @@
+// Package mods parses item mod text into templates and values, so that mods
+// can be compared and queried numerically. For example, the mod
+// "Adds 10 to 20 Fire Damage" has the template "Adds # to # Fire Damage" and
+// the values 10 and 20.
+//
+// A Catalogue loaded from the trade API's stat data maps templates to stat IDs
+// such as "explicit.stat_3299347043", which identify a stat regardless of its
+// wording.
+package mods
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/willroberts/poeapi"
+)
+
+// Type identifies the kind of a mod, matching the prefixes of stat IDs.
+type Type string
+
+// Mod types.
+const (
+	Implicit  Type = "implicit"
+	Explicit  Type = "explicit"
+	Crafted   Type = "crafted"
+	Enchant   Type = "enchant"
+	Fractured Type = "fractured"
+	Utility   Type = "utility"
+	Cosmetic  Type = "cosmetic"
+)
+
+// Mod is a single parsed mod line.
+type Mod struct {
+	// The original text of the mod, such as "+87 to maximum Life".
+	Text string
+
+	// The text with each number replaced by #, such as "+# to maximum Life".
+	Template string
+
+	// The numbers in the text, in order.
+	Values []float64
+
+	Type Type
+
+	// The ID of the stat granted by the mod, when known. For mods granting
+	// several stats, this is the first of StatIDs.
+	StatID string
+
+	// The IDs of every stat granted by the mod, when known from the item's
+	// extended data. Hybrid mods, such as "+# to Strength and Intelligence",
+	// grant more than one.
+	StatIDs []string
+}
+
+// Value returns the value of the mod. For mods with several numbers, such as
+// "Adds # to # Fire Damage", this is their average.
+func (m Mod) Value() float64 {
+	if len(m.Values) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range m.Values {
+		sum += v
+	}
+	return sum / float64(len(m.Values))
+}
+
+// Parse parses a mod line of the given type.
+func Parse(text string, t Type) Mod {
+	template, values := Normalize(text)
+	return Mod{
+		Text:     text,
+		Template: template,
+		Values:   values,
+		Type:     t,
+	}
+}
+
+// Normalize replaces each number in a mod with # and returns the resulting
+// template along with the numbers, e.g. "+87 to maximum Life" becomes
+// "+# to maximum Life" and [87]. Signs are kept in the template, so values
+// are never negative.
+func Normalize(text string) (string, []float64) {
+	var (
+		b      strings.Builder
+		values = make([]float64, 0)
+	)
+	for i := 0; i < len(text); {
+		if !isDigit(text[i]) {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		start := i
+		for i < len(text) && (isDigit(text[i]) || text[i] == '.' && i+1 < len(text) && isDigit(text[i+1])) {
+			i++
+		}
+		v, err := strconv.ParseFloat(text[start:i], 64)
+		if err != nil {
+			b.WriteString(text[start:i])
+			continue
+		}
+		values = append(values, v)
+		b.WriteByte('#')
+	}
+	return b.String(), values
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Mods is a list of parsed mods.
+type Mods []Mod
+
+// Find returns the first mod granting the given stat.
+func (m Mods) Find(statID string) (Mod, bool) {
+	for _, mod := range m {
+		if mod.StatID == statID {
+			return mod, true
+		}
+		for _, id := range mod.StatIDs {
+			if id == statID {
+				return mod, true
+			}
+		}
+	}
+	return Mod{}, false
+}
+
+// FindTemplate returns the first mod with the given template. Templates are
+// compared case-insensitively.
+func (m Mods) FindTemplate(template string) (Mod, bool) {
+	for _, mod := range m {
+		if strings.EqualFold(mod.Template, template) {
+			return mod, true
+		}
+	}
+	return Mod{}, false
+}
+
+// ItemMods parses each of the item's mods. When the item includes extended
+// data, as it does in trade API results, stat IDs are taken from it.
+func ItemMods(item poeapi.Item) Mods {
+	lists := []struct {
+		t     Type
+		lines []string
+	}{
+		{Enchant, item.EnchantMods},
+		{Implicit, item.ImplicitMods},
+		{Fractured, item.FracturedMods},
+		{Explicit, item.ExplicitMods},
+		{Crafted, item.CraftedMods},
+		{Utility, item.UtilityMods},
+		{Cosmetic, item.CosmeticMods},
+	}
+
+	var mods Mods
+	for _, list := range lists {
+		statIDs := extendedStatIDs(item, list.t)
+		for n, line := range list.lines {
+			mod := Parse(line, list.t)
+			if ids := statIDs[n]; len(ids) > 0 {
+				mod.StatID = ids[0]
+				mod.StatIDs = ids
+			}
+			mods = append(mods, mod)
+		}
+	}
+	return mods
+}
+
+// extendedStatIDs returns the stat IDs of each mod line of type t, keyed by
+// line index, from the item's extended data. Each hash lists the lines which
+// grant its stat; a hash without indexes is taken to apply to the line at its
+// own position.
+func extendedStatIDs(item poeapi.Item, t Type) map[int][]string {
+	if item.Extended == nil {
+		return nil
+	}
+	hashes := item.Extended.Hashes[string(t)]
+	statIDs := make(map[int][]string, len(hashes))
+	for n, h := range hashes {
+		if h.Mods == nil {
+			statIDs[n] = append(statIDs[n], h.StatID)
+			continue
+		}
+		for _, line := range h.Mods {
+			statIDs[line] = append(statIDs[line], h.StatID)
+		}
+	}
+	return statIDs
+}
@@ -0,0 +1,151 @@
+package mods
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/willroberts/poeapi"
+)
+
+func TestNormalize(t *testing.T) {
+	template, values := Normalize("Adds 10 to 20.5 Fire Damage")
+	if template != "Adds # to # Fire Damage" {
+		t.Fatalf("unexpected template: %s", template)
+	}
+	if len(values) != 2 || values[0] != 10 || values[1] != 20.5 {
+		t.Fatalf("unexpected values: %v", values)
+	}
+}
+
+func TestParse(t *testing.T) {
+	mod := Parse("+87 to maximum Life", Explicit)
+	if mod.Template != "+# to maximum Life" || mod.Value() != 87 {
+		t.Fatalf("failed to parse mod: %+v", mod)
+	}
+	if v := Parse("Adds 10 to 20 Fire Damage", Explicit).Value(); v != 15 {
+		t.Fatalf("failed to average multi-value mod. expected 15, got %v", v)
+	}
+	if v := Parse("Cannot be Frozen", Explicit).Value(); v != 0 {
+		t.Fatalf("unexpected value for mod without numbers: %v", v)
+	}
+}
+
+func TestItemMods(t *testing.T) {
+	item := poeapi.Item{
+		ImplicitMods: []string{"+20 to maximum Life"},
+		ExplicitMods: []string{"+87 to maximum Life", "Adds 10 to 20 Fire Damage"},
+		CraftedMods:  []string{"+25 to maximum Life"},
+		Extended: &poeapi.ItemExtended{
+			Hashes: map[string][]poeapi.ItemModHash{
+				"explicit": {{StatID: "explicit.stat_3299347043"}},
+			},
+		},
+	}
+	mods := ItemMods(item)
+	if len(mods) != 4 {
+		t.Fatalf("failed to parse item mods: %+v", mods)
+	}
+	if mods[0].Type != Implicit || mods[3].Type != Crafted {
+		t.Fatalf("failed to assign mod types: %+v", mods)
+	}
+	mod, ok := mods.Find("explicit.stat_3299347043")
+	if !ok || mod.Value() != 87 {
+		t.Fatalf("failed to find mod by extended stat id: %+v", mod)
+	}
+	if mods[2].StatID != "" {
+		t.Fatalf("unexpected stat id for mod without hash: %+v", mods[2])
+	}
+	if _, ok := mods.FindTemplate("adds # to # fire damage"); !ok {
+		t.Fatal("failed to find mod by template")
+	}
+}
+
+func TestItemModsWithHybridMod(t *testing.T) {
+	b, err := os.ReadFile("../fixtures/extended-item.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	var item poeapi.Item
+	if err := json.Unmarshal(b, &item); err != nil {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+
+	mods := ItemMods(item)
+	if len(mods) != 3 {
+		t.Fatalf("failed to parse item mods: %+v", mods)
+	}
+	hybrid := mods[1]
+	if hybrid.StatID != "explicit.stat_4080418644" || len(hybrid.StatIDs) != 2 ||
+		hybrid.StatIDs[1] != "explicit.stat_328541901" {
+		t.Fatalf("failed to map hashes of hybrid mod: %+v", hybrid)
+	}
+	if mods[2].StatID != "explicit.stat_3372524247" {
+		t.Fatalf("failed to map hash after hybrid mod: %+v", mods[2])
+	}
+	if mod, ok := mods.Find("explicit.stat_328541901"); !ok || mod.Value() != 30 {
+		t.Fatalf("failed to find hybrid mod by second stat id: %+v", mod)
+	}
+}
+
+func TestLoadCatalogue(t *testing.T) {
+	c, err := LoadCatalogue("../fixtures/stats.json")
+	if err != nil {
+		t.Fatalf("failed to load catalogue: %v", err)
+	}
+	if c.Len() != 6 {
+		t.Fatalf("failed to load all stats. expected 6, got %d", c.Len())
+	}
+	if s, ok := c.Stat("implicit.stat_3299347043"); !ok || s.Type != Implicit {
+		t.Fatalf("failed to get stat by id: %+v", s)
+	}
+	if _, err := LoadCatalogue("../fixtures/missing.json"); err == nil {
+		t.Fatal("failed to detect missing catalogue file")
+	}
+	if _, err := ReadCatalogue(strings.NewReader("{")); err == nil {
+		t.Fatal("failed to detect invalid catalogue")
+	}
+}
+
+func TestCatalogueParse(t *testing.T) {
+	c, err := LoadCatalogue("../fixtures/stats.json")
+	if err != nil {
+		t.Fatalf("failed to load catalogue: %v", err)
+	}
+
+	mod := c.Parse("+87 to maximum Life", Crafted)
+	if mod.StatID != "crafted.stat_3299347043" {
+		t.Fatalf("failed to match stat by type: %+v", mod)
+	}
+
+	mod = c.Parse("-10% to Fire Resistance", Explicit)
+	if mod.StatID != "explicit.stat_3372524247" || mod.Value() != -10 {
+		t.Fatalf("failed to match negative mod: %+v", mod)
+	}
+	if mod.Template != "+#% to Fire Resistance" {
+		t.Fatalf("unexpected template for negative mod: %s", mod.Template)
+	}
+
+	if mod := c.Parse("Cannot be Frozen", Explicit); mod.StatID != "" {
+		t.Fatalf("unexpected stat id for unknown mod: %+v", mod)
+	}
+}
+
+func TestCatalogueItemMods(t *testing.T) {
+	c, err := LoadCatalogue("../fixtures/stats.json")
+	if err != nil {
+		t.Fatalf("failed to load catalogue: %v", err)
+	}
+	item := poeapi.Item{
+		ImplicitMods: []string{"+20 to maximum Life"},
+		ExplicitMods: []string{"+87 to maximum Life", "40% increased Spell Damage"},
+	}
+	mods := c.ItemMods(item)
+	if mod, ok := mods.Find("implicit.stat_3299347043"); !ok || mod.Value() != 20 {
+		t.Fatalf("failed to find implicit mod: %+v", mod)
+	}
+	if mod, ok := mods.Find("explicit.stat_2974417149"); !ok || mod.Value() != 40 {
+		t.Fatalf("failed to find explicit mod: %+v", mod)
+	}
+}
@@ -0,0 +1,454 @@
+package poeapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultOAuthAuthorizeURL is the page where users grant access to an
+	// OAuth client.
+	DefaultOAuthAuthorizeURL = "https://www.pathofexile.com/oauth/authorize"
+
+	// DefaultOAuthTokenURL is the endpoint which issues and refreshes tokens.
+	DefaultOAuthTokenURL = "https://www.pathofexile.com/oauth/token"
+
+	// tokenExpiryDelta renews tokens shortly before they expire, so that a
+	// token does not expire while a request is in flight.
+	tokenExpiryDelta = time.Minute
+
+	// pkceVerifierBytes is the amount of randomness in a PKCE code verifier.
+	// 32 bytes encodes to the minimum verifier length of 43 characters.
+	pkceVerifierBytes = 32
+)
+
+// Scope is an OAuth scope which grants access to a group of endpoints.
+type Scope string
+
+// Scopes supported by the Path of Exile API. Account scopes are granted by a
+// user through the authorization code flow, while service scopes are granted
+// to confidential clients through the client credentials flow.
+const (
+	ScopeAccountProfile        Scope = "account:profile"
+	ScopeAccountLeagues        Scope = "account:leagues"
+	ScopeAccountStashes        Scope = "account:stashes"
+	ScopeAccountCharacters     Scope = "account:characters"
+	ScopeAccountLeagueAccounts Scope = "account:league_accounts"
+	ScopeAccountItemFilter     Scope = "account:item_filter"
+	ScopeServiceLeagues        Scope = "service:leagues"
+	ScopeServiceLeaguesLadder  Scope = "service:leagues:ladder"
+	ScopeServicePVPMatches     Scope = "service:pvp_matches"
+	ScopeServicePSAPI          Scope = "service:psapi"
+)
+
+// endpointScopes declares the scope required to access each endpoint with an
+// OAuth token. Endpoints which are not listed do not require a scope.
+var endpointScopes = map[string]Scope{
+	leaguesEndpoint:    ScopeServiceLeagues,
+	laddersEndpoint:    ScopeServiceLeaguesLadder,
+	pvpMatchesEndpoint: ScopeServicePVPMatches,
+	stashTabsEndpoint:  ScopeServicePSAPI,
+	charactersEndpoint: ScopeAccountCharacters,
+}
+
+// oauthEndpoints lists the endpoints which can only be accessed with an OAuth
+// token. The other endpoints are also served without authentication, so a
+// token is only sent to them when it has the endpoint's scope.
+var oauthEndpoints = map[string]bool{
+	charactersEndpoint: true,
+}
+
+// OAuthConfig describes a client registered with Grinding Gear Games for
+// access to the authenticated API.
+type OAuthConfig struct {
+	// The registered client ID.
+	ClientID string
+
+	// The client secret. Leave blank for public clients, which must use the
+	// authorization code flow with PKCE.
+	ClientSecret string
+
+	// The registered redirect URL for the authorization code flow.
+	RedirectURL string
+
+	// The scopes to request.
+	Scopes []Scope
+
+	// The version of your application, used in the User-Agent header.
+	Version string
+
+	// Contact information for your application, such as an email address,
+	// used in the User-Agent header.
+	Contact string
+
+	// Override the authorization and token URLs for testing purposes.
+	// Defaults to DefaultOAuthAuthorizeURL and DefaultOAuthTokenURL.
+	AuthorizeURL string
+	TokenURL     string
+
+	// The HTTP client used for token requests. Defaults to a client with
+	// DefaultRequestTimeout.
+	HTTPClient *http.Client
+}
+
+// UserAgent returns the User-Agent header required by the API for OAuth
+// clients, e.g. "OAuth myclient/1.0.0 (contact: me@example.com)".
+func (c *OAuthConfig) UserAgent() string {
+	return fmt.Sprintf("OAuth %s/%s (contact: %s)", c.ClientID, c.Version,
+		c.Contact)
+}
+
+// AuthCodeURL returns the URL to send users to in order to grant access. The
+// state value should be random and verified when the user is redirected back.
+func (c *OAuthConfig) AuthCodeURL(state string, pkce PKCE) string {
+	u := url.Values{}
+	u.Add("client_id", c.ClientID)
+	u.Add("response_type", "code")
+	u.Add("scope", joinScopes(c.Scopes))
+	u.Add("state", state)
+	u.Add("redirect_uri", c.RedirectURL)
+	u.Add("code_challenge", pkce.Challenge)
+	u.Add("code_challenge_method", "S256")
+	return fmt.Sprintf("%s?%s", c.authorizeURL(), u.Encode())
+}
+
+// Exchange converts an authorization code into a token, using the PKCE code
+// verifier which was used to build the authorization URL.
+func (c *OAuthConfig) Exchange(ctx context.Context, code string, pkce PKCE) (*Token, error) {
+	u := url.Values{}
+	u.Add("grant_type", "authorization_code")
+	u.Add("code", code)
+	u.Add("redirect_uri", c.RedirectURL)
+	u.Add("scope", joinScopes(c.Scopes))
+	u.Add("code_verifier", pkce.Verifier)
+	return c.requestToken(ctx, u)
+}
+
+// ClientCredentials requests a token for a confidential client. These tokens
+// only grant service scopes, and are not associated with a user.
+func (c *OAuthConfig) ClientCredentials(ctx context.Context) (*Token, error) {
+	if c.ClientSecret == "" {
+		return nil, ErrInvalidOAuthConfig
+	}
+	u := url.Values{}
+	u.Add("grant_type", "client_credentials")
+	u.Add("scope", joinScopes(c.Scopes))
+	return c.requestToken(ctx, u)
+}
+
+// Refresh exchanges a refresh token for a new token.
+func (c *OAuthConfig) Refresh(ctx context.Context, refreshToken string) (*Token, error) {
+	u := url.Values{}
+	u.Add("grant_type", "refresh_token")
+	u.Add("refresh_token", refreshToken)
+	return c.requestToken(ctx, u)
+}
+
+// TokenSource returns a TokenSource which starts from the given token and
+// renews it as it expires, either with its refresh token or, for confidential
+// clients, with the client credentials flow. The token may be nil for
+// confidential clients, in which case one is requested on first use.
+func (c *OAuthConfig) TokenSource(t *Token) TokenSource {
+	return &oauthTokenSource{config: c, token: t}
+}
+
+func (c *OAuthConfig) requestToken(ctx context.Context, form url.Values) (*Token, error) {
+	form.Set("client_id", c.ClientID)
+	if c.ClientSecret != "" {
+		form.Set("client_secret", c.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL(),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", c.UserAgent())
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newOAuthError(resp.StatusCode, b)
+	}
+	return parseTokenResponse(b, time.Now())
+}
+
+func (c *OAuthConfig) authorizeURL() string {
+	if c.AuthorizeURL != "" {
+		return c.AuthorizeURL
+	}
+	return DefaultOAuthAuthorizeURL
+}
+
+func (c *OAuthConfig) tokenURL() string {
+	if c.TokenURL != "" {
+		return c.TokenURL
+	}
+	return DefaultOAuthTokenURL
+}
+
+func (c *OAuthConfig) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return &http.Client{Timeout: DefaultRequestTimeout}
+}
+
+func validateOAuthConfig(c *OAuthConfig) error {
+	if c.ClientID == "" || c.Version == "" || c.Contact == "" {
+		return ErrInvalidOAuthConfig
+	}
+	return nil
+}
+
+// Token is an OAuth access token.
+type Token struct {
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       time.Time
+
+	// The space-separated scopes granted to the token.
+	Scope string
+
+	// The account name and UUID of the user who granted the token. These are
+	// empty for client credentials tokens.
+	Username string
+	Subject  string
+}
+
+// Valid reports whether the token is present and not about to expire.
+func (t *Token) Valid() bool {
+	if t == nil || t.AccessToken == "" {
+		return false
+	}
+	return t.Expiry.IsZero() || time.Now().Add(tokenExpiryDelta).Before(t.Expiry)
+}
+
+// HasScope reports whether the token was granted the given scope. Tokens whose
+// scopes are unknown are assumed to have every scope.
+func (t *Token) HasScope(scope Scope) bool {
+	if t.Scope == "" {
+		return true
+	}
+	for _, s := range strings.Fields(t.Scope) {
+		if Scope(s) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type"`
+	Scope        string `json:"scope"`
+	Username     string `json:"username"`
+	Subject      string `json:"sub"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+func parseTokenResponse(b []byte, now time.Time) (*Token, error) {
+	var resp tokenResponse
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, err
+	}
+	if resp.AccessToken == "" {
+		return nil, ErrInvalidToken
+	}
+	t := &Token{
+		AccessToken:  resp.AccessToken,
+		TokenType:    resp.TokenType,
+		RefreshToken: resp.RefreshToken,
+		Scope:        resp.Scope,
+		Username:     resp.Username,
+		Subject:      resp.Subject,
+	}
+	if resp.ExpiresIn > 0 {
+		t.Expiry = now.Add(time.Duration(resp.ExpiresIn) * time.Second)
+	}
+	return t, nil
+}
+
+// TokenSource supplies OAuth tokens for API requests. Implementations must be
+// safe for concurrent use.
+type TokenSource interface {
+	Token(context.Context) (*Token, error)
+}
+
+// StaticTokenSource returns a TokenSource which always returns the given
+// token, without renewing it. Requests fail with ErrInvalidToken once the
+// token expires.
+func StaticTokenSource(t *Token) TokenSource {
+	return staticTokenSource{token: t}
+}
+
+type staticTokenSource struct {
+	token *Token
+}
+
+func (s staticTokenSource) Token(context.Context) (*Token, error) {
+	return s.token, nil
+}
+
+type oauthTokenSource struct {
+	config *OAuthConfig
+	token  *Token
+	lock   sync.Mutex
+}
+
+// Token returns the current token, renewing it first if it has expired.
+func (s *oauthTokenSource) Token(ctx context.Context) (*Token, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.token.Valid() {
+		return s.token, nil
+	}
+
+	var (
+		t   *Token
+		err error
+	)
+	switch {
+	case s.token != nil && s.token.RefreshToken != "":
+		t, err = s.config.Refresh(ctx, s.token.RefreshToken)
+	case s.config.ClientSecret != "":
+		t, err = s.config.ClientCredentials(ctx)
+	default:
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Refresh responses may omit the refresh token when it is unchanged.
+	if t.RefreshToken == "" && s.token != nil {
+		t.RefreshToken = s.token.RefreshToken
+	}
+	s.token = t
+	return t, nil
+}
+
+// PKCE holds a Proof Key for Code Exchange verifier and its S256 challenge.
+// Generate a new PKCE for every authorization request.
+type PKCE struct {
+	Verifier  string
+	Challenge string
+}
+
+// NewPKCE generates a random code verifier and its challenge.
+func NewPKCE() (PKCE, error) {
+	b := make([]byte, pkceVerifierBytes)
+	if _, err := rand.Read(b); err != nil {
+		return PKCE{}, err
+	}
+	verifier := base64.RawURLEncoding.EncodeToString(b)
+	return PKCE{Verifier: verifier, Challenge: pkceChallenge(verifier)}, nil
+}
+
+func pkceChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// OAuthError is returned when the token endpoint rejects a request.
+type OAuthError struct {
+	StatusCode  int
+	Code        string
+	Description string
+}
+
+// Error implements the error interface.
+func (e *OAuthError) Error() string {
+	if e.Description != "" {
+		return fmt.Sprintf("oauth error %q (status %d): %s", e.Code,
+			e.StatusCode, e.Description)
+	}
+	return fmt.Sprintf("oauth error %q (status %d)", e.Code, e.StatusCode)
+}
+
+func newOAuthError(statusCode int, body []byte) *OAuthError {
+	e := &OAuthError{StatusCode: statusCode}
+	var b struct {
+		Error       string `json:"error"`
+		Description string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &b); err == nil {
+		e.Code = b.Error
+		e.Description = b.Description
+	}
+	return e
+}
+
+func joinScopes(scopes []Scope) string {
+	s := make([]string, len(scopes))
+	for i, scope := range scopes {
+		s[i] = string(scope)
+	}
+	return strings.Join(s, " ")
+}
+
+// authorize adds the User-Agent and Authorization headers to requests sent to
+// the API host. Requests to other hosts, such as poe.ninja, are left untouched
+// so that tokens are never sent to third parties. Requests to public endpoints
+// are sent without a token when it lacks the endpoint's scope, while
+// ErrMissingScope is returned for endpoints which require OAuth.
+func (c *client) authorize(ctx context.Context, req *http.Request) error {
+	if c.oauth == nil || req.URL.Host != c.host {
+		return nil
+	}
+	req.Header.Set("User-Agent", c.oauth.UserAgent())
+	if c.tokenSource == nil {
+		return nil
+	}
+
+	t, err := c.tokenSource.Token(ctx)
+	if err != nil {
+		return err
+	}
+	if !t.Valid() {
+		return ErrInvalidToken
+	}
+	endpoint := endpointPath(req.URL.Path)
+	if scope, ok := endpointScopes[endpoint]; ok && !t.HasScope(scope) {
+		if oauthEndpoints[endpoint] {
+			return ErrMissingScope
+		}
+		return nil
+	}
+	req.Header.Set("Authorization", "Bearer "+t.AccessToken)
+	return nil
+}
+
+// endpointPath returns the first segment of a URL path, such as "/ladders"
+// for "/ladders/Standard".
+func endpointPath(path string) string {
+	if i := strings.Index(strings.TrimPrefix(path, "/"), "/"); i >= 0 {
+		return path[:i+1]
+	}
+	return path
+}
@@ -0,0 +1,286 @@
+package poeapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testOAuthConfig = OAuthConfig{
+	ClientID:     "test",
+	ClientSecret: "secret",
+	RedirectURL:  "http://localhost/callback",
+	Scopes:       []Scope{ScopeServiceLeagues, ScopeServicePSAPI},
+	Version:      "1.0.0",
+	Contact:      "test@example.com",
+	TokenURL:     fmt.Sprintf("http://%s%s", testHost, tokenEndpoint),
+	HTTPClient:   testClient,
+}
+
+func TestOAuthUserAgent(t *testing.T) {
+	expected := "OAuth test/1.0.0 (contact: test@example.com)"
+	if ua := testOAuthConfig.UserAgent(); ua != expected {
+		t.Fatalf("unexpected user agent: expected %s, got %s", expected, ua)
+	}
+}
+
+func TestNewPKCE(t *testing.T) {
+	p, err := NewPKCE()
+	if err != nil {
+		t.Fatalf("failed to create pkce: %v", err)
+	}
+	if len(p.Verifier) < 43 {
+		t.Fatalf("pkce verifier too short: %d", len(p.Verifier))
+	}
+	if p.Challenge != pkceChallenge(p.Verifier) {
+		t.Fatal("pkce challenge does not match verifier")
+	}
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// Example from RFC 7636, Appendix B.
+	var (
+		verifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+		challenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+	)
+	if c := pkceChallenge(verifier); c != challenge {
+		t.Fatalf("unexpected pkce challenge: expected %s, got %s", challenge, c)
+	}
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	cfg := testOAuthConfig
+	raw := cfg.AuthCodeURL("xyz", PKCE{Challenge: "abc"})
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatalf("failed to parse auth code url: %v", err)
+	}
+	if !strings.HasPrefix(raw, DefaultOAuthAuthorizeURL+"?") {
+		t.Fatalf("unexpected authorize url: %s", raw)
+	}
+	q := u.Query()
+	expected := map[string]string{
+		"client_id":             "test",
+		"response_type":         "code",
+		"scope":                 "service:leagues service:psapi",
+		"state":                 "xyz",
+		"redirect_uri":          "http://localhost/callback",
+		"code_challenge":        "abc",
+		"code_challenge_method": "S256",
+	}
+	for k, v := range expected {
+		if q.Get(k) != v {
+			t.Fatalf("unexpected %s: expected %s, got %s", k, v, q.Get(k))
+		}
+	}
+}
+
+func TestOAuthExchange(t *testing.T) {
+	cfg := testOAuthConfig
+	tok, err := cfg.Exchange(context.Background(), "code", PKCE{Verifier: "v"})
+	if err != nil {
+		t.Fatalf("failed to exchange code: %v", err)
+	}
+	if tok.AccessToken != "code-token" || tok.RefreshToken != "refresh" {
+		t.Fatalf("unexpected token: %+v", tok)
+	}
+	if !tok.Valid() {
+		t.Fatal("exchanged token is not valid")
+	}
+}
+
+func TestOAuthClientCredentials(t *testing.T) {
+	cfg := testOAuthConfig
+	tok, err := cfg.ClientCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get client credentials token: %v", err)
+	}
+	if tok.AccessToken != "client_credentials-token" {
+		t.Fatalf("unexpected access token: %s", tok.AccessToken)
+	}
+	if !tok.HasScope(ScopeServicePSAPI) || tok.HasScope(ScopeAccountStashes) {
+		t.Fatalf("unexpected token scopes: %s", tok.Scope)
+	}
+}
+
+func TestOAuthClientCredentialsPublicClient(t *testing.T) {
+	cfg := testOAuthConfig
+	cfg.ClientSecret = ""
+	if _, err := cfg.ClientCredentials(context.Background()); err != ErrInvalidOAuthConfig {
+		t.Fatal("failed to reject client credentials for public client")
+	}
+}
+
+func TestOAuthError(t *testing.T) {
+	cfg := testOAuthConfig
+	cfg.ClientID = "unknown"
+	_, err := cfg.ClientCredentials(context.Background())
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) {
+		t.Fatalf("expected *OAuthError, got %v", err)
+	}
+	if oauthErr.Code != "invalid_client" || oauthErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("unexpected oauth error: %v", oauthErr)
+	}
+}
+
+func TestOAuthTokenSourceRefresh(t *testing.T) {
+	var (
+		cfg     = testOAuthConfig
+		expired = &Token{
+			AccessToken:  "old",
+			RefreshToken: "refresh",
+			Expiry:       time.Now().Add(-time.Hour),
+		}
+		ts = cfg.TokenSource(expired)
+	)
+	tok, err := ts.Token(context.Background())
+	if err != nil {
+		t.Fatalf("failed to refresh token: %v", err)
+	}
+	if tok.AccessToken != "refresh_token-token" {
+		t.Fatalf("unexpected refreshed token: %s", tok.AccessToken)
+	}
+
+	again, err := ts.Token(context.Background())
+	if err != nil || again != tok {
+		t.Fatal("token source did not reuse valid token")
+	}
+}
+
+func TestOAuthTokenSourceExpiredPublicClient(t *testing.T) {
+	cfg := testOAuthConfig
+	cfg.ClientSecret = ""
+	ts := cfg.TokenSource(&Token{AccessToken: "old", Expiry: time.Now()})
+	if _, err := ts.Token(context.Background()); err != ErrInvalidToken {
+		t.Fatal("failed to detect unrenewable token")
+	}
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	var (
+		headers   = make(map[string]http.Header)
+		transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			headers[req.URL.Host] = req.Header
+			return &http.Response{
+				StatusCode: http.StatusOK,
+				Header:     http.Header{},
+				Body:       ioutil.NopCloser(strings.NewReader(`{}`)),
+				Request:    req,
+			}, nil
+		})
+		cfg  = testOAuthConfig
+		opts = DefaultClientOptions
+	)
+	opts.UseCache = false
+	opts.Transport = transport
+	opts.OAuth = &cfg
+	opts.TokenSource = StaticTokenSource(&Token{
+		AccessToken: "abc",
+		Scope:       "service:leagues",
+	})
+
+	c, err := NewAPIClient(opts)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	if _, err := c.GetLeague(GetLeagueOptions{ID: "Standard"}); err != nil {
+		t.Fatalf("failed to get league: %v", err)
+	}
+	h := headers[DefaultHost]
+	if h.Get("Authorization") != "Bearer abc" {
+		t.Fatalf("unexpected authorization header: %s", h.Get("Authorization"))
+	}
+	if h.Get("User-Agent") != cfg.UserAgent() {
+		t.Fatalf("unexpected user agent: %s", h.Get("User-Agent"))
+	}
+
+	if _, err := c.GetLatestStashID(); err != nil {
+		t.Fatalf("failed to get latest stash id: %v", err)
+	}
+	if headers[DefaultNinjaHost].Get("Authorization") != "" {
+		t.Fatal("sent oauth token to third-party host")
+	}
+
+	// Public endpoints are still available without the scope, but must not
+	// receive the token.
+	if _, err := c.GetStashes(GetStashOptions{}); err != nil {
+		t.Fatalf("failed to get stashes without scope: %v", err)
+	}
+	if headers[DefaultHost].Get("Authorization") != "" {
+		t.Fatal("sent oauth token without the endpoint's scope")
+	}
+	if _, err := c.GetLadder(GetLadderOptions{ID: "Standard"}); err != nil {
+		t.Fatalf("failed to get ladder without scope: %v", err)
+	}
+	if headers[DefaultHost].Get("Authorization") != "" {
+		t.Fatal("sent oauth token without the endpoint's scope")
+	}
+
+	if _, err := c.GetCharacters(GetCharactersOptions{}); err != ErrMissingScope {
+		t.Fatalf("failed to detect missing scope: %v", err)
+	}
+}
+
+func TestAuthorizeWithInvalidToken(t *testing.T) {
+	tokens := map[string]*Token{
+		"nil":     nil,
+		"expired": {AccessToken: "abc", Expiry: time.Now().Add(-time.Minute)},
+	}
+	for name, token := range tokens {
+		sent := false
+		cfg := testOAuthConfig
+		opts := DefaultClientOptions
+		opts.UseCache = false
+		opts.Transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			sent = true
+			return nil, errors.New("unexpected request")
+		})
+		opts.OAuth = &cfg
+		opts.TokenSource = StaticTokenSource(token)
+
+		c, err := NewAPIClient(opts)
+		if err != nil {
+			t.Fatalf("failed to create client: %v", err)
+		}
+		if _, err := c.GetLeague(GetLeagueOptions{ID: "Standard"}); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("failed to detect %s token: %v", name, err)
+		}
+		if sent {
+			t.Fatalf("sent request with %s token", name)
+		}
+	}
+}
+
+func TestValidateOptionsOAuth(t *testing.T) {
+	opts := DefaultClientOptions
+	opts.OAuth = &OAuthConfig{ClientID: "test"}
+	if err := validateClientOptions(opts); err != ErrInvalidOAuthConfig {
+		t.Fatal("failed to detect incomplete oauth config")
+	}
+
+	opts.OAuth = nil
+	opts.TokenSource = StaticTokenSource(&Token{AccessToken: "abc"})
+	if err := validateClientOptions(opts); err != ErrInvalidOAuthConfig {
+		t.Fatal("failed to detect token source without oauth config")
+	}
+}
+
+func TestEndpointPath(t *testing.T) {
+	cases := map[string]string{
+		"/ladders/Standard":  "/ladders",
+		"/leagues":           "/leagues",
+		"/public-stash-tabs": "/public-stash-tabs",
+	}
+	for path, expected := range cases {
+		if p := endpointPath(path); p != expected {
+			t.Fatalf("unexpected endpoint path for %s: %s", path, p)
+		}
+	}
+}
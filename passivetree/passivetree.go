@@ -0,0 +1,334 @@
+// Package passivetree decodes and encodes the passive skill tree URLs used by
+// the official Path of Exile website, such as:
+//
+//	https://www.pathofexile.com/passive-skill-tree/AAAABgMBAQ...
+//
+// The URL payload is URL-safe base64 encoding of a binary structure holding a
+// version number, the character class and ascendancy, the allocated node
+// hashes, allocated cluster jewel node hashes, and chosen mastery effects.
+// Versions 4 through 6 of the format are supported; Encode always produces
+// version 6.
+package passivetree
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/willroberts/poeapi"
+)
+
+const (
+	// DefaultURLPrefix is prepended to encoded trees by URL.
+	DefaultURLPrefix = "https://www.pathofexile.com/passive-skill-tree/"
+
+	// Version is the format version written by Encode.
+	Version = 6
+
+	minVersion = 4
+
+	// Cluster jewel node hashes start at 65536, but are stored as their
+	// offset from this value in order to fit in two bytes.
+	clusterNodeOffset = 65536
+
+	// Each list in the payload is prefixed with a one-byte length.
+	maxListLength = 255
+)
+
+var (
+	// ErrInvalidTree is raised when a tree URL cannot be decoded.
+	ErrInvalidTree = errors.New("invalid passive tree")
+
+	// ErrUnsupportedVersion is raised when a tree URL uses a format version
+	// which is not supported.
+	ErrUnsupportedVersion = errors.New("unsupported passive tree version")
+
+	// ErrInvalidNode is raised when a node hash cannot be encoded.
+	ErrInvalidNode = errors.New("invalid passive tree node")
+
+	// ErrTooManyNodes is raised when a list of nodes or masteries is too long
+	// to be encoded.
+	ErrTooManyNodes = errors.New("too many passive tree nodes")
+
+	// ErrInvalidClass is raised when a class or ascendancy is not recognized.
+	ErrInvalidClass = errors.New("invalid class")
+)
+
+// Class is a character class, as numbered in the passive tree format.
+type Class int
+
+// Character classes.
+const (
+	Scion Class = iota
+	Marauder
+	Ranger
+	Witch
+	Duelist
+	Templar
+	Shadow
+)
+
+var classNames = []string{
+	"Scion", "Marauder", "Ranger", "Witch", "Duelist", "Templar", "Shadow",
+}
+
+// ascendancyNames lists each class's ascendancies in the order used by the
+// passive tree format. Ascendancy 0 means no ascendancy.
+var ascendancyNames = map[Class][]string{
+	Scion:    {"Ascendant"},
+	Marauder: {"Juggernaut", "Berserker", "Chieftain"},
+	Ranger:   {"Raider", "Deadeye", "Pathfinder"},
+	Witch:    {"Occultist", "Elementalist", "Necromancer"},
+	Duelist:  {"Slayer", "Gladiator", "Champion"},
+	Templar:  {"Inquisitor", "Hierophant", "Guardian"},
+	Shadow:   {"Assassin", "Trickster", "Saboteur"},
+}
+
+// String returns the name of the class.
+func (c Class) String() string {
+	if c < 0 || int(c) >= len(classNames) {
+		return "Class(" + strconv.Itoa(int(c)) + ")"
+	}
+	return classNames[c]
+}
+
+// AscendancyName returns the name of the given ascendancy of the class, or an
+// empty string when there is no such ascendancy.
+func (c Class) AscendancyName(ascendancy int) string {
+	names := ascendancyNames[c]
+	if ascendancy < 1 || ascendancy > len(names) {
+		return ""
+	}
+	return names[ascendancy-1]
+}
+
+// ParseClass finds the class and ascendancy for a class or ascendancy name,
+// such as "Witch" or "Necromancer". The ascendancy is 0 for base classes.
+func ParseClass(name string) (Class, int, error) {
+	for i, n := range classNames {
+		if strings.EqualFold(n, name) {
+			return Class(i), 0, nil
+		}
+	}
+	for class, names := range ascendancyNames {
+		for i, n := range names {
+			if strings.EqualFold(n, name) {
+				return class, i + 1, nil
+			}
+		}
+	}
+	return 0, 0, ErrInvalidClass
+}
+
+// Tree is a decoded passive skill tree.
+type Tree struct {
+	// The format version the tree was decoded from.
+	Version int
+
+	Class      Class
+	Ascendancy int
+
+	// The hashes of allocated nodes, including ascendancy nodes.
+	Nodes []int
+
+	// The hashes of allocated cluster jewel nodes. These are always 65536 or
+	// greater.
+	ClusterNodes []int
+
+	// The chosen effect of each allocated mastery, keyed by mastery node hash.
+	MasteryEffects map[int]int
+}
+
+// FromPassives builds a tree from a character's passives, as returned by
+// poeapi's GetPassives. The ascendancy name is read from Passives.Ascendancy.
+func FromPassives(p poeapi.Passives) (Tree, error) {
+	class, ascendancy, err := ParseClass(p.Ascendancy)
+	if err != nil {
+		return Tree{}, err
+	}
+	t := Tree{
+		Version:        Version,
+		Class:          class,
+		Ascendancy:     ascendancy,
+		Nodes:          append([]int{}, p.Hashes...),
+		ClusterNodes:   append([]int{}, p.HashesEx...),
+		MasteryEffects: make(map[int]int, len(p.MasteryEffects)),
+	}
+	for node, effect := range p.MasteryEffects {
+		hash, err := strconv.Atoi(node)
+		if err != nil {
+			return Tree{}, ErrInvalidNode
+		}
+		t.MasteryEffects[hash] = effect
+	}
+	return t, nil
+}
+
+// Decode parses a passive tree from either a full URL or its payload.
+func Decode(s string) (Tree, error) {
+	if i := strings.IndexAny(s, "?#"); i >= 0 {
+		s = s[:i]
+	}
+	s = strings.TrimRight(s[strings.LastIndex(s, "/")+1:], "=")
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Tree{}, fmt.Errorf("%w: %v", ErrInvalidTree, err)
+	}
+	return decode(b)
+}
+
+func decode(b []byte) (Tree, error) {
+	if len(b) < 7 {
+		return Tree{}, ErrInvalidTree
+	}
+	t := Tree{
+		Version:        int(binary.BigEndian.Uint32(b[0:4])),
+		Class:          Class(b[4]),
+		Ascendancy:     int(b[5]),
+		Nodes:          make([]int, 0),
+		ClusterNodes:   make([]int, 0),
+		MasteryEffects: make(map[int]int),
+	}
+	if t.Version < minVersion || t.Version > Version {
+		return Tree{}, ErrUnsupportedVersion
+	}
+
+	// Version 4 stores a fullscreen flag in byte 6, followed by node hashes
+	// until the end of the payload.
+	if t.Version == 4 {
+		nodes := b[7:]
+		if len(nodes)%2 != 0 {
+			return Tree{}, ErrInvalidTree
+		}
+		for i := 0; i < len(nodes); i += 2 {
+			t.Nodes = append(t.Nodes, int(binary.BigEndian.Uint16(nodes[i:])))
+		}
+		return t, nil
+	}
+
+	r := reader{b: b, pos: 6}
+	nodes, err := r.list(2)
+	if err != nil {
+		return Tree{}, err
+	}
+	for _, n := range nodes {
+		t.Nodes = append(t.Nodes, int(binary.BigEndian.Uint16(n)))
+	}
+
+	clusterNodes, err := r.list(2)
+	if err != nil {
+		return Tree{}, err
+	}
+	for _, n := range clusterNodes {
+		t.ClusterNodes = append(t.ClusterNodes,
+			int(binary.BigEndian.Uint16(n))+clusterNodeOffset)
+	}
+
+	if t.Version < 6 {
+		return t, nil
+	}
+	masteries, err := r.list(4)
+	if err != nil {
+		return Tree{}, err
+	}
+	for _, m := range masteries {
+		effect := int(binary.BigEndian.Uint16(m[0:2]))
+		node := int(binary.BigEndian.Uint16(m[2:4]))
+		t.MasteryEffects[node] = effect
+	}
+	return t, nil
+}
+
+// reader reads length-prefixed lists from a tree payload.
+type reader struct {
+	b   []byte
+	pos int
+}
+
+// list reads a one-byte count followed by that many entries of the given size.
+func (r *reader) list(size int) ([][]byte, error) {
+	if r.pos >= len(r.b) {
+		return nil, ErrInvalidTree
+	}
+	count := int(r.b[r.pos])
+	r.pos++
+	end := r.pos + count*size
+	if end > len(r.b) {
+		return nil, ErrInvalidTree
+	}
+	entries := make([][]byte, count)
+	for i := range entries {
+		entries[i] = r.b[r.pos+i*size : r.pos+(i+1)*size]
+	}
+	r.pos = end
+	return entries, nil
+}
+
+// Encode returns the version 6 payload for the tree.
+func (t Tree) Encode() (string, error) {
+	if t.Class < 0 || int(t.Class) >= len(classNames) {
+		return "", ErrInvalidClass
+	}
+	if t.Ascendancy < 0 || t.Ascendancy > len(ascendancyNames[t.Class]) {
+		return "", ErrInvalidClass
+	}
+	if len(t.Nodes) > maxListLength || len(t.ClusterNodes) > maxListLength ||
+		len(t.MasteryEffects) > maxListLength {
+		return "", ErrTooManyNodes
+	}
+
+	b := make([]byte, 4, 9+2*len(t.Nodes)+2*len(t.ClusterNodes)+4*len(t.MasteryEffects))
+	binary.BigEndian.PutUint32(b, Version)
+	b = append(b, byte(t.Class), byte(t.Ascendancy))
+
+	b = append(b, byte(len(t.Nodes)))
+	for _, n := range t.Nodes {
+		if n < 0 || n >= clusterNodeOffset {
+			return "", ErrInvalidNode
+		}
+		b = appendUint16(b, n)
+	}
+
+	b = append(b, byte(len(t.ClusterNodes)))
+	for _, n := range t.ClusterNodes {
+		if n < clusterNodeOffset || n >= 2*clusterNodeOffset {
+			return "", ErrInvalidNode
+		}
+		b = appendUint16(b, n-clusterNodeOffset)
+	}
+
+	// Sort masteries so that encoding is deterministic.
+	masteries := make([]int, 0, len(t.MasteryEffects))
+	for node := range t.MasteryEffects {
+		masteries = append(masteries, node)
+	}
+	sort.Ints(masteries)
+	b = append(b, byte(len(masteries)))
+	for _, node := range masteries {
+		effect := t.MasteryEffects[node]
+		if node < 0 || node >= clusterNodeOffset || effect < 0 || effect >= clusterNodeOffset {
+			return "", ErrInvalidNode
+		}
+		b = appendUint16(b, effect)
+		b = appendUint16(b, node)
+	}
+
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// URL returns the official website URL for the tree.
+func (t Tree) URL() (string, error) {
+	payload, err := t.Encode()
+	if err != nil {
+		return "", err
+	}
+	return DefaultURLPrefix + payload, nil
+}
+
+func appendUint16(b []byte, n int) []byte {
+	return append(b, byte(n>>8), byte(n))
+}
@@ -0,0 +1,128 @@
+package passivetree
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/willroberts/poeapi"
+)
+
+// testPayload is a version 6 Witch (Necromancer) tree with two nodes, one
+// cluster jewel node, and one mastery effect.
+var testPayload = base64.URLEncoding.EncodeToString([]byte{
+	0, 0, 0, 6, // Version.
+	3, 3, // Class, ascendancy.
+	2, 0x11, 0x2d, 0x84, 0x8a, // Nodes: 4397, 33930.
+	1, 0x00, 0x05, // Cluster nodes: 65541.
+	1, 0xbd, 0x01, 0x83, 0x5f, // Masteries: 33631 -> 48385.
+})
+
+func TestDecode(t *testing.T) {
+	tree, err := Decode(DefaultURLPrefix + testPayload + "?accountName=a&characterName=b")
+	if err != nil {
+		t.Fatalf("failed to decode tree: %v", err)
+	}
+	expected := Tree{
+		Version:        6,
+		Class:          Witch,
+		Ascendancy:     3,
+		Nodes:          []int{4397, 33930},
+		ClusterNodes:   []int{65541},
+		MasteryEffects: map[int]int{33631: 48385},
+	}
+	if !reflect.DeepEqual(tree, expected) {
+		t.Fatalf("unexpected tree: expected %+v, got %+v", expected, tree)
+	}
+	if name := tree.Class.AscendancyName(tree.Ascendancy); name != "Necromancer" {
+		t.Fatalf("unexpected ascendancy: %s", name)
+	}
+}
+
+func TestDecodeVersion4(t *testing.T) {
+	payload := base64.URLEncoding.EncodeToString([]byte{
+		0, 0, 0, 4, 1, 2, 0, 0x11, 0x2d, 0x84, 0x8a,
+	})
+	tree, err := Decode(payload)
+	if err != nil {
+		t.Fatalf("failed to decode version 4 tree: %v", err)
+	}
+	if tree.Class != Marauder || tree.Ascendancy != 2 ||
+		!reflect.DeepEqual(tree.Nodes, []int{4397, 33930}) {
+		t.Fatalf("unexpected version 4 tree: %+v", tree)
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	cases := map[string]error{
+		"!!!": ErrInvalidTree,
+		base64.URLEncoding.EncodeToString([]byte{0, 0, 0, 6}):                ErrInvalidTree,
+		base64.URLEncoding.EncodeToString([]byte{0, 0, 0, 9, 0, 0, 0}):       ErrUnsupportedVersion,
+		base64.URLEncoding.EncodeToString([]byte{0, 0, 0, 6, 0, 0, 5, 1, 2}): ErrInvalidTree,
+	}
+	for payload, expected := range cases {
+		if _, err := Decode(payload); !errors.Is(err, expected) {
+			t.Fatalf("unexpected error for %s: expected %v, got %v", payload,
+				expected, err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	tree, err := Decode(testPayload)
+	if err != nil {
+		t.Fatalf("failed to decode tree: %v", err)
+	}
+	payload, err := tree.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode tree: %v", err)
+	}
+	if payload != testPayload {
+		t.Fatalf("failed to round trip tree: expected %s, got %s", testPayload,
+			payload)
+	}
+}
+
+func TestEncodeInvalidNode(t *testing.T) {
+	trees := []Tree{
+		{Class: Witch, Nodes: []int{70000}},
+		{Class: Witch, ClusterNodes: []int{5}},
+		{Class: Witch, Ascendancy: 4},
+		{Class: Class(9)},
+	}
+	for _, tree := range trees {
+		if _, err := tree.Encode(); err == nil {
+			t.Fatalf("failed to detect invalid tree: %+v", tree)
+		}
+	}
+}
+
+func TestFromPassives(t *testing.T) {
+	tree, err := FromPassives(poeapi.Passives{
+		Hashes:         []int{4397, 33930},
+		HashesEx:       []int{65541},
+		MasteryEffects: map[string]int{"33631": 48385},
+		Ascendancy:     "Necromancer",
+	})
+	if err != nil {
+		t.Fatalf("failed to convert passives: %v", err)
+	}
+	payload, err := tree.Encode()
+	if err != nil {
+		t.Fatalf("failed to encode tree: %v", err)
+	}
+	if payload != testPayload {
+		t.Fatalf("unexpected payload: expected %s, got %s", testPayload, payload)
+	}
+}
+
+func TestParseClass(t *testing.T) {
+	class, ascendancy, err := ParseClass("witch")
+	if err != nil || class != Witch || ascendancy != 0 {
+		t.Fatal("failed to parse base class")
+	}
+	if _, _, err := ParseClass("Bard"); err != ErrInvalidClass {
+		t.Fatal("failed to detect invalid class")
+	}
+}
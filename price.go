@@ -0,0 +1,311 @@
+package poeapi
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	buyoutPrefix = "~b/o"
+	fixedPrefix  = "~price"
+)
+
+// PriceMode distinguishes between asking prices and fixed prices.
+type PriceMode int
+
+const (
+	// PriceBuyout is an asking price set with "~b/o". Buyers may negotiate.
+	PriceBuyout PriceMode = iota + 1
+
+	// PriceFixed is an exact price set with "~price".
+	PriceFixed
+)
+
+// String returns the note prefix for the mode.
+func (m PriceMode) String() string {
+	switch m {
+	case PriceBuyout:
+		return buyoutPrefix
+	case PriceFixed:
+		return fixedPrefix
+	default:
+		return ""
+	}
+}
+
+// Currency is a canonical currency code, as used by the official trade site.
+type Currency string
+
+// Canonical currency codes.
+const (
+	CurrencyChaos      Currency = "chaos"
+	CurrencyDivine     Currency = "divine"
+	CurrencyExalted    Currency = "exalted"
+	CurrencyAlchemy    Currency = "alch"
+	CurrencyAlteration Currency = "alt"
+	CurrencyAnnulment  Currency = "annul"
+	CurrencyAugment    Currency = "aug"
+	CurrencyBlessed    Currency = "blessed"
+	CurrencyChance     Currency = "chance"
+	CurrencyChisel     Currency = "chisel"
+	CurrencyChromatic  Currency = "chrome"
+	CurrencyFusing     Currency = "fusing"
+	CurrencyGemcutter  Currency = "gcp"
+	CurrencyJeweller   Currency = "jew"
+	CurrencyMirror     Currency = "mirror"
+	CurrencyPortal     Currency = "portal"
+	CurrencyRegal      Currency = "regal"
+	CurrencyRegret     Currency = "regret"
+	CurrencyScouring   Currency = "scour"
+	CurrencySilver     Currency = "silver"
+	CurrencyTransmute  Currency = "transmute"
+	CurrencyVaal       Currency = "vaal"
+	CurrencyWisdom     Currency = "wisdom"
+)
+
+// currencyNames maps canonical currency codes to their in-game names.
+var currencyNames = map[Currency]string{
+	CurrencyChaos:      "Chaos Orb",
+	CurrencyDivine:     "Divine Orb",
+	CurrencyExalted:    "Exalted Orb",
+	CurrencyAlchemy:    "Orb of Alchemy",
+	CurrencyAlteration: "Orb of Alteration",
+	CurrencyAnnulment:  "Orb of Annulment",
+	CurrencyAugment:    "Orb of Augmentation",
+	CurrencyBlessed:    "Blessed Orb",
+	CurrencyChance:     "Orb of Chance",
+	CurrencyChisel:     "Cartographer's Chisel",
+	CurrencyChromatic:  "Chromatic Orb",
+	CurrencyFusing:     "Orb of Fusing",
+	CurrencyGemcutter:  "Gemcutter's Prism",
+	CurrencyJeweller:   "Jeweller's Orb",
+	CurrencyMirror:     "Mirror of Kalandra",
+	CurrencyPortal:     "Portal Scroll",
+	CurrencyRegal:      "Regal Orb",
+	CurrencyRegret:     "Orb of Regret",
+	CurrencyScouring:   "Orb of Scouring",
+	CurrencySilver:     "Silver Coin",
+	CurrencyTransmute:  "Orb of Transmutation",
+	CurrencyVaal:       "Vaal Orb",
+	CurrencyWisdom:     "Scroll of Wisdom",
+}
+
+// currencyAliases maps common abbreviations and plurals to canonical codes.
+// Canonical codes and in-game names are also accepted by ParseCurrency.
+var currencyAliases = map[string]Currency{
+	"c":              CurrencyChaos,
+	"div":            CurrencyDivine,
+	"divines":        CurrencyDivine,
+	"ex":             CurrencyExalted,
+	"exa":            CurrencyExalted,
+	"exalt":          CurrencyExalted,
+	"exalts":         CurrencyExalted,
+	"alchemy":        CurrencyAlchemy,
+	"alts":           CurrencyAlteration,
+	"alteration":     CurrencyAlteration,
+	"annulment":      CurrencyAnnulment,
+	"augmentation":   CurrencyAugment,
+	"cartographer":   CurrencyChisel,
+	"chisels":        CurrencyChisel,
+	"chrom":          CurrencyChromatic,
+	"chromatic":      CurrencyChromatic,
+	"chromatics":     CurrencyChromatic,
+	"chromes":        CurrencyChromatic,
+	"fuse":           CurrencyFusing,
+	"fuses":          CurrencyFusing,
+	"fusings":        CurrencyFusing,
+	"gemcutter":      CurrencyGemcutter,
+	"gemcutters":     CurrencyGemcutter,
+	"jeweller":       CurrencyJeweller,
+	"jewellers":      CurrencyJeweller,
+	"kalandra":       CurrencyMirror,
+	"mir":            CurrencyMirror,
+	"port":           CurrencyPortal,
+	"regals":         CurrencyRegal,
+	"regrets":        CurrencyRegret,
+	"scouring":       CurrencyScouring,
+	"scours":         CurrencyScouring,
+	"transmutation":  CurrencyTransmute,
+	"transmutations": CurrencyTransmute,
+	"wis":            CurrencyWisdom,
+}
+
+// Name returns the in-game name of the currency, or the currency code itself
+// if it is not in the currency table.
+func (c Currency) Name() string {
+	if name, ok := currencyNames[c]; ok {
+		return name
+	}
+	return string(c)
+}
+
+// ParseCurrency converts a currency code, alias, or in-game name into a
+// canonical currency code. The lookup is case-insensitive.
+func ParseCurrency(s string) (Currency, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if _, ok := currencyNames[Currency(s)]; ok {
+		return Currency(s), true
+	}
+	if c, ok := currencyAliases[s]; ok {
+		return c, true
+	}
+	for c, name := range currencyNames {
+		if strings.ToLower(name) == s {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// Price is a parsed price note.
+type Price struct {
+	Mode     PriceMode
+	Amount   float64
+	Currency Currency
+}
+
+// String formats the price as a note, such as "~b/o 5 chaos".
+func (p Price) String() string {
+	return p.Mode.String() + " " + strconv.FormatFloat(p.Amount, 'f', -1, 64) +
+		" " + string(p.Currency)
+}
+
+// ExchangeRates maps currencies to their value in chaos orbs.
+type ExchangeRates map[Currency]float64
+
+// ChaosValue converts the price into chaos orbs using the given rates. Chaos
+// orbs are always worth one chaos orb, even if absent from the rates.
+func (p Price) ChaosValue(rates ExchangeRates) (float64, error) {
+	if p.Currency == CurrencyChaos {
+		return p.Amount, nil
+	}
+	rate, ok := rates[p.Currency]
+	if !ok {
+		return 0, ErrUnknownExchangeRate
+	}
+	return p.Amount * rate, nil
+}
+
+// ParsePrice parses a price note such as "~b/o 5 chaos", "~price 2.5 exa", or
+// "~price 1/3 divine". ErrNoPrice is returned for notes which do not start
+// with "~b/o" or "~price", and ErrInvalidPrice for malformed price notes.
+func ParsePrice(note string) (Price, error) {
+	fields := strings.Fields(note)
+	if len(fields) == 0 {
+		return Price{}, ErrNoPrice
+	}
+
+	var p Price
+	switch strings.ToLower(fields[0]) {
+	case buyoutPrefix:
+		p.Mode = PriceBuyout
+	case fixedPrefix:
+		p.Mode = PriceFixed
+	default:
+		return Price{}, ErrNoPrice
+	}
+
+	// The currency may be separated from the amount, as in "5 chaos", or
+	// attached to it, as in "5chaos".
+	var amount, currency string
+	switch len(fields) {
+	case 2:
+		i := strings.IndexFunc(fields[1], func(r rune) bool {
+			return (r < '0' || r > '9') && r != '.' && r != '/' && r != ','
+		})
+		if i <= 0 {
+			return Price{}, ErrInvalidPrice
+		}
+		amount, currency = fields[1][:i], fields[1][i:]
+	case 3:
+		amount, currency = fields[1], fields[2]
+	default:
+		return Price{}, ErrInvalidPrice
+	}
+
+	n, err := parsePriceAmount(amount)
+	if err != nil {
+		return Price{}, err
+	}
+	p.Amount = n
+
+	c, ok := ParseCurrency(currency)
+	if !ok {
+		return Price{}, ErrInvalidPrice
+	}
+	p.Currency = c
+	return p, nil
+}
+
+// parsePriceAmount parses decimal amounts, including those using a comma as
+// the decimal separator, and ratios such as "1/3".
+func parsePriceAmount(s string) (float64, error) {
+	if i := strings.Index(s, "/"); i >= 0 {
+		num, err := parseDecimal(s[:i])
+		if err != nil {
+			return 0, err
+		}
+		den, err := parseDecimal(s[i+1:])
+		if err != nil || den == 0 || !isFinite(num/den) {
+			return 0, ErrInvalidPrice
+		}
+		return num / den, nil
+	}
+	return parseDecimal(s)
+}
+
+// parseDecimal parses a non-negative decimal number. A comma is only treated
+// as the decimal separator when it is the sole separator and is followed by
+// one or two digits, as in "1,5". Other commas, such as the thousands
+// separator in "1,500", are ambiguous and rejected, as are the special values
+// and exponents accepted by strconv.
+func parseDecimal(s string) (float64, error) {
+	if i := strings.Index(s, ","); i >= 0 {
+		frac := s[i+1:]
+		if strings.Contains(s[:i], ".") || len(frac) == 0 || len(frac) > 2 ||
+			strings.Trim(frac, "0123456789") != "" {
+			return 0, ErrInvalidPrice
+		}
+		s = s[:i] + "." + frac
+	}
+	if !isDecimal(s) {
+		return 0, ErrInvalidPrice
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil || n < 0 || !isFinite(n) {
+		return 0, ErrInvalidPrice
+	}
+	return n, nil
+}
+
+// isDecimal reports whether s consists of digits with at most one '.'.
+func isDecimal(s string) bool {
+	digits, dots := 0, 0
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits++
+		case r == '.':
+			dots++
+		default:
+			return false
+		}
+	}
+	return digits > 0 && dots <= 1
+}
+
+func isFinite(f float64) bool {
+	return !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+// ItemPrice returns the price of an item listed in the given stash. As in the
+// game, an item's own note takes precedence, and items without a price note
+// inherit the price from the stash tab's name.
+func ItemPrice(item Item, stash Stash) (Price, error) {
+	p, err := ParsePrice(item.Note)
+	if err != ErrNoPrice {
+		return p, err
+	}
+	return ParsePrice(stash.Index)
+}
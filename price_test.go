@@ -0,0 +1,137 @@
+package poeapi
+
+import "testing"
+
+func TestParsePrice(t *testing.T) {
+	cases := map[string]Price{
+		"~b/o 5 chaos":       {Mode: PriceBuyout, Amount: 5, Currency: CurrencyChaos},
+		"~price 2.5 exa":     {Mode: PriceFixed, Amount: 2.5, Currency: CurrencyExalted},
+		"~price 1/4 divine":  {Mode: PriceFixed, Amount: 0.25, Currency: CurrencyDivine},
+		"~b/o 10c":           {Mode: PriceBuyout, Amount: 10, Currency: CurrencyChaos},
+		"~price 1,5 div":     {Mode: PriceFixed, Amount: 1.5, Currency: CurrencyDivine},
+		"~price 0,25 div":    {Mode: PriceFixed, Amount: 0.25, Currency: CurrencyDivine},
+		"~B/O 3 Chromatics":  {Mode: PriceBuyout, Amount: 3, Currency: CurrencyChromatic},
+		"  ~price  1 mirror": {Mode: PriceFixed, Amount: 1, Currency: CurrencyMirror},
+	}
+	for note, expected := range cases {
+		p, err := ParsePrice(note)
+		if err != nil {
+			t.Fatalf("failed to parse price %q: %v", note, err)
+		}
+		if p != expected {
+			t.Fatalf("unexpected price for %q: expected %+v, got %+v", note,
+				expected, p)
+		}
+	}
+}
+
+func TestParsePriceErrors(t *testing.T) {
+	cases := map[string]error{
+		"":                   ErrNoPrice,
+		"$3":                 ErrNoPrice,
+		"selling cheap":      ErrNoPrice,
+		"~price":             ErrInvalidPrice,
+		"~price five chaos":  ErrInvalidPrice,
+		"~price 5 bananas":   ErrInvalidPrice,
+		"~price 1/0 chaos":   ErrInvalidPrice,
+		"~price -1 chaos":    ErrInvalidPrice,
+		"~b/o 5 chaos each":  ErrInvalidPrice,
+		"~price 1,000 chaos": ErrInvalidPrice,
+		"~price 1,500 chaos": ErrInvalidPrice,
+		"~price 1.5,5 chaos": ErrInvalidPrice,
+		"~price 1, chaos":    ErrInvalidPrice,
+		"~b/o nan chaos":     ErrInvalidPrice,
+		"~b/o NaN chaos":     ErrInvalidPrice,
+		"~b/o inf chaos":     ErrInvalidPrice,
+		"~b/o NaN/1 chaos":   ErrInvalidPrice,
+		"~b/o 1/inf chaos":   ErrInvalidPrice,
+		"~b/o 1e3 chaos":     ErrInvalidPrice,
+		"~b/o 1.2.3 chaos":   ErrInvalidPrice,
+	}
+	for note, expected := range cases {
+		if _, err := ParsePrice(note); err != expected {
+			t.Fatalf("unexpected error for %q: expected %v, got %v", note,
+				expected, err)
+		}
+	}
+}
+
+func TestPriceString(t *testing.T) {
+	p := Price{Mode: PriceBuyout, Amount: 2.5, Currency: CurrencyExalted}
+	if s := p.String(); s != "~b/o 2.5 exalted" {
+		t.Fatalf("unexpected price string: %s", s)
+	}
+}
+
+func TestParseCurrency(t *testing.T) {
+	cases := map[string]Currency{
+		"chaos":              CurrencyChaos,
+		"EXA":                CurrencyExalted,
+		"Mirror of Kalandra": CurrencyMirror,
+		"gcp":                CurrencyGemcutter,
+	}
+	for s, expected := range cases {
+		if c, ok := ParseCurrency(s); !ok || c != expected {
+			t.Fatalf("unexpected currency for %q: %s", s, c)
+		}
+	}
+	if _, ok := ParseCurrency("bananas"); ok {
+		t.Fatal("failed to reject unknown currency")
+	}
+	if name := CurrencyDivine.Name(); name != "Divine Orb" {
+		t.Fatalf("unexpected currency name: %s", name)
+	}
+}
+
+func TestChaosValue(t *testing.T) {
+	rates := ExchangeRates{CurrencyDivine: 200}
+	p := Price{Mode: PriceFixed, Amount: 1.5, Currency: CurrencyDivine}
+	if v, err := p.ChaosValue(rates); err != nil || v != 300 {
+		t.Fatalf("unexpected chaos value: %v, %v", v, err)
+	}
+	p = Price{Mode: PriceFixed, Amount: 7, Currency: CurrencyChaos}
+	if v, err := p.ChaosValue(nil); err != nil || v != 7 {
+		t.Fatalf("unexpected chaos value for chaos price: %v, %v", v, err)
+	}
+	p.Currency = CurrencyMirror
+	if _, err := p.ChaosValue(rates); err != ErrUnknownExchangeRate {
+		t.Fatal("failed to detect missing exchange rate")
+	}
+}
+
+func TestItemPrice(t *testing.T) {
+	stash := Stash{Index: "~price 1 exa"}
+	p, err := ItemPrice(Item{Note: "~b/o 5 chaos"}, stash)
+	if err != nil || p.Currency != CurrencyChaos {
+		t.Fatalf("failed to prefer item note: %+v, %v", p, err)
+	}
+	p, err = ItemPrice(Item{}, stash)
+	if err != nil || p.Currency != CurrencyExalted || p.Mode != PriceFixed {
+		t.Fatalf("failed to fall back to stash tab price: %+v, %v", p, err)
+	}
+	if _, err := ItemPrice(Item{}, Stash{Index: "$3"}); err != ErrNoPrice {
+		t.Fatal("failed to detect unpriced item")
+	}
+}
+
+func TestItemPriceFromFixture(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+	priced := 0
+	for _, s := range stashes.Stashes {
+		for _, i := range s.Items {
+			if _, err := ItemPrice(i, s); err == nil {
+				priced++
+			}
+		}
+	}
+	if priced != 3 {
+		t.Fatalf("unexpected priced item count: expected 3, got %d", priced)
+	}
+}
@@ -0,0 +1,332 @@
+package poeapi
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// ValueType describes how a property value is displayed in game, which
+// indicates whether it has been modified or the type of damage it represents.
+type ValueType int64
+
+// Property value types.
+const (
+	ValueDefault ValueType = iota
+	ValueAugmented
+	ValueUnmet
+	ValuePhysical
+	ValueFire
+	ValueCold
+	ValueLightning
+	ValueChaos
+)
+
+var valueTypeNames = map[ValueType]string{
+	ValueDefault:   "Default",
+	ValueAugmented: "Augmented",
+	ValueUnmet:     "Unmet",
+	ValuePhysical:  "Physical",
+	ValueFire:      "Fire",
+	ValueCold:      "Cold",
+	ValueLightning: "Lightning",
+	ValueChaos:     "Chaos",
+}
+
+// String returns the name of the value type, such as "Fire".
+func (v ValueType) String() string {
+	if name, ok := valueTypeNames[v]; ok {
+		return name
+	}
+	return "ValueType(" + strconv.FormatInt(int64(v), 10) + ")"
+}
+
+// PropertyValue is a single value of a property or requirement. The API
+// encodes these as tuples of text and value type, such as ["+20%", 1], which
+// are decoded by Property.TypedValues and Requirement.TypedValues.
+type PropertyValue struct {
+	Text string
+	Type ValueType
+}
+
+// newPropertyValue decodes a value from its tuple form. Missing or malformed
+// elements are left as zero values.
+func newPropertyValue(tuple []interface{}) PropertyValue {
+	var v PropertyValue
+	if len(tuple) > 0 {
+		v.Text, _ = tuple[0].(string)
+	}
+	if len(tuple) > 1 {
+		switch t := tuple[1].(type) {
+		case float64:
+			v.Type = ValueType(t)
+		case int:
+			v.Type = ValueType(t)
+		case int64:
+			v.Type = ValueType(t)
+		case ValueType:
+			v.Type = t
+		}
+	}
+	return v
+}
+
+// Tuple returns the value in its tuple form, as stored in Property.Values.
+func (v PropertyValue) Tuple() []interface{} {
+	return []interface{}{v.Text, int64(v.Type)}
+}
+
+// typedValues decodes each tuple of a property or requirement.
+func typedValues(tuples [][]interface{}) []PropertyValue {
+	values := make([]PropertyValue, 0, len(tuples))
+	for _, t := range tuples {
+		values = append(values, newPropertyValue(t))
+	}
+	return values
+}
+
+// valueTuples encodes values in their tuple form.
+func valueTuples(values []PropertyValue) [][]interface{} {
+	tuples := make([][]interface{}, 0, len(values))
+	for _, v := range values {
+		tuples = append(tuples, v.Tuple())
+	}
+	return tuples
+}
+
+// UnmarshalJSON decodes a PropertyValue from its tuple form.
+func (v *PropertyValue) UnmarshalJSON(b []byte) error {
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(b, &tuple); err != nil {
+		return err
+	}
+	if len(tuple) > 0 {
+		if err := json.Unmarshal(tuple[0], &v.Text); err != nil {
+			return err
+		}
+	}
+	if len(tuple) > 1 {
+		if err := json.Unmarshal(tuple[1], &v.Type); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MarshalJSON encodes a PropertyValue in its tuple form.
+func (v PropertyValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{v.Text, v.Type})
+}
+
+// IsPercent reports whether the value is a percentage, such as "+20%".
+func (v PropertyValue) IsPercent() bool {
+	return strings.HasSuffix(strings.TrimSpace(v.Text), "%")
+}
+
+// Range parses the value as a number or a range of numbers, such as "10-20".
+// Signs, percentages and thousands separators are ignored. For a single
+// number, the minimum and maximum are equal.
+func (v PropertyValue) Range() (ValueRange, bool) {
+	s := strings.TrimSpace(v.Text)
+	s = strings.TrimSuffix(s, "%")
+	s = strings.TrimPrefix(s, "+")
+	s = strings.ReplaceAll(s, ",", "")
+
+	// A dash at the start of the value is a sign rather than a range
+	// separator.
+	if n := strings.LastIndex(s, "-"); n > 0 {
+		lo, err := strconv.ParseFloat(strings.TrimSpace(s[:n]), 64)
+		if err != nil {
+			return ValueRange{}, false
+		}
+		hi, err := strconv.ParseFloat(strings.TrimSpace(s[n+1:]), 64)
+		if err != nil {
+			return ValueRange{}, false
+		}
+		return ValueRange{Min: lo, Max: hi}, true
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return ValueRange{}, false
+	}
+	return ValueRange{Min: f, Max: f}, true
+}
+
+// Number parses the value as a number. Ranges are averaged.
+func (v PropertyValue) Number() (float64, bool) {
+	r, ok := v.Range()
+	if !ok {
+		return 0, false
+	}
+	return r.Average(), true
+}
+
+// ValueRange is a numeric range parsed from a property value.
+type ValueRange struct {
+	Min float64
+	Max float64
+}
+
+// Average returns the midpoint of the range.
+func (r ValueRange) Average() float64 {
+	return (r.Min + r.Max) / 2
+}
+
+// TypedValues decodes the property's value tuples.
+func (p Property) TypedValues() []PropertyValue {
+	return typedValues(p.Values)
+}
+
+// Value returns the text of the property's first value, or an empty string if
+// it has none.
+func (p Property) Value() string {
+	if len(p.Values) == 0 {
+		return ""
+	}
+	return newPropertyValue(p.Values[0]).Text
+}
+
+// Number parses the property's first value as a number. Ranges are averaged.
+func (p Property) Number() (float64, bool) {
+	if len(p.Values) == 0 {
+		return 0, false
+	}
+	return newPropertyValue(p.Values[0]).Number()
+}
+
+// TypedValues decodes the requirement's value tuples.
+func (r Requirement) TypedValues() []PropertyValue {
+	return typedValues(r.Values)
+}
+
+// Value returns the text of the requirement's first value, or an empty string
+// if it has none.
+func (r Requirement) Value() string {
+	if len(r.Values) == 0 {
+		return ""
+	}
+	return newPropertyValue(r.Values[0]).Text
+}
+
+// Number parses the requirement's first value as a number.
+func (r Requirement) Number() (float64, bool) {
+	if len(r.Values) == 0 {
+		return 0, false
+	}
+	return newPropertyValue(r.Values[0]).Number()
+}
+
+// Met reports whether the character viewing the item meets the requirement.
+// This is only meaningful for endpoints which return a character's items.
+func (r Requirement) Met() bool {
+	for _, v := range r.TypedValues() {
+		if v.Type == ValueUnmet {
+			return false
+		}
+	}
+	return true
+}
+
+// requirementAliases maps the full names of attributes to the abbreviations
+// which the API sometimes uses instead.
+var requirementAliases = map[string]string{
+	"Strength":     "Str",
+	"Dexterity":    "Dex",
+	"Intelligence": "Int",
+}
+
+// Property returns the item property with the given name, such as "Quality".
+func (i Item) Property(name string) (Property, bool) {
+	for _, p := range i.Properties {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Property{}, false
+}
+
+// Requirement returns the item requirement with the given name, such as
+// "Level". Attributes may be given by their full names or abbreviations.
+func (i Item) Requirement(name string) (Requirement, bool) {
+	alias := requirementAliases[name]
+	for _, r := range i.Requirements {
+		if r.Name == name || (alias != "" && r.Name == alias) ||
+			requirementAliases[r.Name] == name {
+			return r, true
+		}
+	}
+	return Requirement{}, false
+}
+
+// propertyNumber returns the numeric value of the named property, or zero.
+func (i Item) propertyNumber(name string) float64 {
+	p, ok := i.Property(name)
+	if !ok {
+		return 0
+	}
+	n, _ := p.Number()
+	return n
+}
+
+// Quality returns the item's quality, or zero.
+func (i Item) Quality() int {
+	return int(i.propertyNumber("Quality"))
+}
+
+// Armour returns the item's armour, or zero.
+func (i Item) Armour() int {
+	return int(i.propertyNumber("Armour"))
+}
+
+// Evasion returns the item's evasion rating, or zero.
+func (i Item) Evasion() int {
+	return int(i.propertyNumber("Evasion Rating"))
+}
+
+// EnergyShield returns the item's energy shield, or zero.
+func (i Item) EnergyShield() int {
+	return int(i.propertyNumber("Energy Shield"))
+}
+
+// AttacksPerSecond returns the weapon's attack speed, or zero.
+func (i Item) AttacksPerSecond() float64 {
+	return i.propertyNumber("Attacks per Second")
+}
+
+// damagePerHit returns the average damage per hit of the named damage
+// property, summing each value for properties such as "Elemental Damage"
+// which hold several ranges.
+func (i Item) damagePerHit(name string) float64 {
+	p, ok := i.Property(name)
+	if !ok {
+		return 0
+	}
+	total := 0.0
+	for _, v := range p.TypedValues() {
+		if n, ok := v.Number(); ok {
+			total += n
+		}
+	}
+	return total
+}
+
+// PhysicalDPS returns the weapon's physical damage per second, or zero.
+func (i Item) PhysicalDPS() float64 {
+	return i.damagePerHit("Physical Damage") * i.AttacksPerSecond()
+}
+
+// ElementalDPS returns the weapon's elemental damage per second, or zero.
+func (i Item) ElementalDPS() float64 {
+	return i.damagePerHit("Elemental Damage") * i.AttacksPerSecond()
+}
+
+// ChaosDPS returns the weapon's chaos damage per second, or zero.
+func (i Item) ChaosDPS() float64 {
+	return i.damagePerHit("Chaos Damage") * i.AttacksPerSecond()
+}
+
+// DPS returns the weapon's total damage per second, or zero.
+func (i Item) DPS() float64 {
+	return i.PhysicalDPS() + i.ElementalDPS() + i.ChaosDPS()
+}
@@ -0,0 +1,123 @@
+package poeapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParsePropertyValues(t *testing.T) {
+	var p Property
+	b := []byte(`{"name":"Elemental Damage","values":[["10-20",4],["1-3",6]],"displayMode":0}`)
+	if err := json.Unmarshal(b, &p); err != nil {
+		t.Fatalf("failed to decode property: %v", err)
+	}
+	values := p.TypedValues()
+	if len(values) != 2 || values[0].Type != ValueFire || values[1].Type != ValueLightning {
+		t.Fatalf("failed to decode property values: %+v", values)
+	}
+	if p.Value() != "10-20" || values[0].Type.String() != "Fire" {
+		t.Fatalf("failed to decode property value text: %+v", values[0])
+	}
+
+	out, err := json.Marshal(values[0])
+	if err != nil {
+		t.Fatalf("failed to encode property value: %v", err)
+	}
+	if string(out) != `["10-20",4]` {
+		t.Fatalf("failed to encode property value tuple. got %s", out)
+	}
+}
+
+func TestPropertyValueRange(t *testing.T) {
+	cases := map[string]ValueRange{
+		"10-20":  {Min: 10, Max: 20},
+		"+20%":   {Min: 20, Max: 20},
+		"-5%":    {Min: -5, Max: -5},
+		"1,338":  {Min: 1338, Max: 1338},
+		"1.50":   {Min: 1.5, Max: 1.5},
+		"6.50%":  {Min: 6.5, Max: 6.5},
+		"5 - 10": {Min: 5, Max: 10},
+	}
+	for text, expected := range cases {
+		r, ok := PropertyValue{Text: text}.Range()
+		if !ok || r != expected {
+			t.Fatalf("failed to parse range %q. expected %+v, got %+v", text, expected, r)
+		}
+	}
+	for _, text := range []string{"", "4 sec", "1/15249"} {
+		if _, ok := (PropertyValue{Text: text}).Range(); ok {
+			t.Fatalf("failed to reject non-numeric value %q", text)
+		}
+	}
+	if !(PropertyValue{Text: "+20%"}).IsPercent() {
+		t.Fatal("failed to detect percentage")
+	}
+}
+
+func TestItemPropertyLookups(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+	gem := stashes.Stashes[0].Items[0]
+
+	if gem.Quality() != 20 {
+		t.Fatalf("failed to get quality. expected 20, got %d", gem.Quality())
+	}
+	q, ok := gem.Property("Quality")
+	if !ok || q.TypedValues()[0].Type != ValueAugmented {
+		t.Fatalf("failed to get quality property: %+v", q)
+	}
+	if _, ok := gem.Property("Armour"); ok {
+		t.Fatal("failed to detect missing property")
+	}
+
+	level, ok := gem.Requirement("Level")
+	if !ok {
+		t.Fatal("failed to get level requirement")
+	}
+	if n, _ := level.Number(); n != 12 {
+		t.Fatalf("failed to parse level requirement. expected 12, got %v", n)
+	}
+	if _, ok := gem.Requirement("Dexterity"); !ok {
+		t.Fatal("failed to get requirement by full attribute name")
+	}
+	if !level.Met() {
+		t.Fatal("failed to detect met requirement")
+	}
+}
+
+func TestItemDefences(t *testing.T) {
+	item := Item{Properties: []Property{
+		{Name: "Armour", Values: [][]interface{}{{"1338", float64(ValueAugmented)}}},
+		{Name: "Energy Shield", Values: [][]interface{}{{"16", 0.0}}},
+	}}
+	if item.Armour() != 1338 || item.EnergyShield() != 16 || item.Evasion() != 0 {
+		t.Fatalf("failed to get defences: %d, %d, %d",
+			item.Armour(), item.EnergyShield(), item.Evasion())
+	}
+}
+
+func TestItemDPS(t *testing.T) {
+	item := Item{Properties: []Property{
+		{Name: "Physical Damage", Values: [][]interface{}{{"100-200", float64(ValueAugmented)}}},
+		{Name: "Elemental Damage", Values: [][]interface{}{
+			{"10-20", float64(ValueFire)},
+			{"5-15", float64(ValueCold)},
+		}},
+		{Name: "Attacks per Second", Values: [][]interface{}{{"1.50", float64(ValueAugmented)}}},
+	}}
+	if item.PhysicalDPS() != 225 {
+		t.Fatalf("failed to calculate physical dps. expected 225, got %v", item.PhysicalDPS())
+	}
+	if item.ElementalDPS() != 37.5 {
+		t.Fatalf("failed to calculate elemental dps. expected 37.5, got %v", item.ElementalDPS())
+	}
+	if item.DPS() != 262.5 {
+		t.Fatalf("failed to calculate total dps. expected 262.5, got %v", item.DPS())
+	}
+}
@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -50,12 +51,16 @@ func validateGetPVPMatchesOptions(opts GetPVPMatchesOptions) error {
 }
 
 func (c *client) GetPVPMatches(opts GetPVPMatchesOptions) ([]PVPMatch, error) {
+	return c.GetPVPMatchesContext(context.Background(), opts)
+}
+
+func (c *client) GetPVPMatchesContext(ctx context.Context, opts GetPVPMatchesOptions) ([]PVPMatch, error) {
 	if err := validateGetPVPMatchesOptions(opts); err != nil {
 		return []PVPMatch{}, err
 	}
 	url := fmt.Sprintf("%s?%s", c.formatURL(pvpMatchesEndpoint),
 		opts.toQueryParams())
-	resp, err := c.get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return []PVPMatch{}, err
 	}
@@ -1,6 +1,11 @@
 package poeapi
 
 import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 )
@@ -8,10 +13,22 @@ import (
 const (
 	// UnlimitedRate disables rate limiting when used as a rate limit.
 	UnlimitedRate = 0
+
+	// policyMargin is added to every rate limit window to allow for latency
+	// between the client recording a request and the API receiving it.
+	policyMargin = 100 * time.Millisecond
 )
 
-// ratelimiter uses blocking time.Sleep calls to prevent callers from sending
-// requests too frequently. ratelimiter is threadsafe.
+// ratelimiter uses blocking, context-aware sleeps to prevent callers from
+// sending requests too frequently. ratelimiter is threadsafe.
+//
+// The API publishes its rate limits in X-Rate-Limit-* response headers. Each
+// endpoint belongs to a named policy, and each policy has one or more rules
+// (such as per-IP or per-account) with several windows, e.g. "5:10:60" allows
+// five requests per ten seconds and restricts offenders for sixty seconds.
+// Once a response from an endpoint has been seen, requests to that endpoint
+// are scheduled to stay within every window of its policy. Until then, the
+// static rateLimit and stashRateLimit intervals are used.
 type ratelimiter struct {
 	rateLimit      float64
 	stashRateLimit float64
@@ -21,32 +38,304 @@ type ratelimiter struct {
 
 	lock      sync.Mutex
 	stashLock sync.Mutex
+
+	// endpoints maps endpoint keys to policy names, and policies maps policy
+	// names to their current state. Both are guarded by policyLock.
+	endpoints  map[string]string
+	policies   map[string]*ratePolicy
+	policyLock sync.Mutex
 }
 
-// Wait blocks execution until enough time has elapsed since the last request.
-func (r *ratelimiter) Wait(stash bool) {
+// Wait blocks execution until a request may be sent to the given endpoint. If
+// the context is canceled first, Wait returns the context's error and the
+// request slot is not consumed.
+func (r *ratelimiter) Wait(ctx context.Context, endpoint string, stash bool) error {
+	if p := r.policy(endpoint); p != nil {
+		return p.Wait(ctx)
+	}
+
 	if stash {
 		r.stashLock.Lock()
 		defer r.stashLock.Unlock()
-		r.waitLimit(r.stashRateLimit, r.lastStashRequest)
+		if err := r.waitLimit(ctx, r.stashRateLimit, r.lastStashRequest); err != nil {
+			return err
+		}
 		r.lastStashRequest = time.Now()
-		return
+		return nil
 	}
 
 	r.lock.Lock()
 	defer r.lock.Unlock()
-	r.waitLimit(r.rateLimit, r.lastRequest)
+	if err := r.waitLimit(ctx, r.rateLimit, r.lastRequest); err != nil {
+		return err
+	}
 	r.lastRequest = time.Now()
+	return nil
 }
 
-func (r *ratelimiter) waitLimit(ratelimit float64, last time.Time) {
-	if ratelimit == UnlimitedRate {
+// Update records the rate limit policy reported in a response from the given
+// endpoint. Responses without rate limit headers are ignored.
+func (r *ratelimiter) Update(endpoint string, header http.Header) {
+	name, rules, ok := parseRateLimitHeaders(header)
+	if !ok {
 		return
 	}
+
+	r.policyLock.Lock()
+	p, exists := r.policies[name]
+	if !exists {
+		p = &ratePolicy{}
+		r.policies[name] = p
+	}
+	r.endpoints[endpoint] = name
+	r.policyLock.Unlock()
+
+	p.Update(rules, time.Now())
+}
+
+func (r *ratelimiter) policy(endpoint string) *ratePolicy {
+	r.policyLock.Lock()
+	defer r.policyLock.Unlock()
+	name, ok := r.endpoints[endpoint]
+	if !ok {
+		return nil
+	}
+	return r.policies[name]
+}
+
+func (r *ratelimiter) waitLimit(ctx context.Context, ratelimit float64, last time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if ratelimit == UnlimitedRate {
+		return nil
+	}
 	interval := time.Duration(1000.0/ratelimit) * time.Millisecond
 	elapsed := time.Since(last)
 	if elapsed < interval {
-		time.Sleep(interval - elapsed)
+		return sleepContext(ctx, interval-elapsed)
+	}
+	return nil
+}
+
+// ratePolicy tracks the windows of a single rate limit policy along with the
+// times of recent requests made under it.
+type ratePolicy struct {
+	// waitLock serializes callers of Wait, and is held while sleeping.
+	waitLock sync.Mutex
+
+	// lock guards windows, and is never held while sleeping.
+	windows []*rateWindow
+	lock    sync.Mutex
+}
+
+// rateWindow is a single window of a rate limit rule.
+type rateWindow struct {
+	maxHits    int
+	period     time.Duration
+	restricted time.Time
+	hits       []time.Time
+}
+
+// Wait blocks until a request can be sent without exceeding any window of the
+// policy, then records the request.
+func (p *ratePolicy) Wait(ctx context.Context) error {
+	p.waitLock.Lock()
+	defer p.waitLock.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		p.lock.Lock()
+		now := time.Now()
+		delay := p.delay(now)
+		if delay <= 0 {
+			for _, w := range p.windows {
+				w.hits = append(w.hits, now)
+			}
+			p.lock.Unlock()
+			return nil
+		}
+		p.lock.Unlock()
+
+		// The policy may be updated while sleeping, so recompute the delay
+		// afterwards instead of assuming the slot is free.
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// delay returns how long to wait before the next request may be sent. The
+// caller must hold p.lock.
+func (p *ratePolicy) delay(now time.Time) time.Duration {
+	var delay time.Duration
+	for _, w := range p.windows {
+		w.prune(now)
+		if d := w.restricted.Sub(now); d > delay {
+			delay = d
+		}
+		if w.maxHits > 0 && len(w.hits) >= w.maxHits {
+			oldest := w.hits[len(w.hits)-w.maxHits]
+			if d := oldest.Add(w.period + policyMargin).Sub(now); d > delay {
+				delay = d
+			}
+		}
+	}
+	return delay
+}
+
+// Update replaces the policy's windows with those reported by the API. Local
+// request history is kept for windows whose period is unchanged, and is padded
+// when the API has counted more requests than the client has recorded, such
+// as when other processes share the same IP address.
+func (p *ratePolicy) Update(rules []rateLimitRule, now time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	previous := make(map[time.Duration]*rateWindow, len(p.windows))
+	for _, w := range p.windows {
+		previous[w.period] = w
+	}
+
+	windows := make([]*rateWindow, 0, len(rules))
+	for _, rule := range rules {
+		w := &rateWindow{maxHits: rule.maxHits, period: rule.period}
+		if old, ok := previous[rule.period]; ok {
+			w.hits = old.hits
+			w.restricted = old.restricted
+		}
+		w.prune(now)
+		for i := len(w.hits); i < rule.hits; i++ {
+			w.hits = append(w.hits, now)
+		}
+		if rule.restricted > 0 {
+			w.restricted = now.Add(rule.restricted)
+		}
+		windows = append(windows, w)
+	}
+	p.windows = windows
+}
+
+// prune discards requests which have fallen out of the window.
+func (w *rateWindow) prune(now time.Time) {
+	cutoff := now.Add(-(w.period + policyMargin))
+	i := 0
+	for i < len(w.hits) && !w.hits[i].After(cutoff) {
+		i++
+	}
+	w.hits = w.hits[i:]
+}
+
+// rateLimitRule is a single window parsed from the rate limit headers.
+type rateLimitRule struct {
+	maxHits    int
+	period     time.Duration
+	hits       int
+	restricted time.Duration
+}
+
+// parseRateLimitHeaders reads the policy name and the windows of all of its
+// rules from the X-Rate-Limit-* headers. For example:
+//
+//	X-Rate-Limit-Policy: ladder-view
+//	X-Rate-Limit-Rules: Ip
+//	X-Rate-Limit-Ip: 5:10:60,15:60:300
+//	X-Rate-Limit-Ip-State: 1:10:0,1:60:0
+func parseRateLimitHeaders(header http.Header) (string, []rateLimitRule, bool) {
+	name := header.Get("X-Rate-Limit-Policy")
+	if name == "" {
+		return "", nil, false
+	}
+
+	rules := make([]rateLimitRule, 0)
+	for _, rule := range strings.Split(header.Get("X-Rate-Limit-Rules"), ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		limits := parseRateLimitTriples(header.Get("X-Rate-Limit-" + rule))
+		states := parseRateLimitTriples(header.Get("X-Rate-Limit-" + rule + "-State"))
+		for _, l := range limits {
+			r := rateLimitRule{
+				maxHits: l[0],
+				period:  time.Duration(l[1]) * time.Second,
+			}
+			// State windows are matched to limit windows by period.
+			for _, s := range states {
+				if s[1] == l[1] {
+					r.hits = s[0]
+					r.restricted = time.Duration(s[2]) * time.Second
+					break
+				}
+			}
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return "", nil, false
+	}
+	return name, rules, true
+}
+
+// parseRateLimitTriples parses a comma-separated list of colon-separated
+// integer triples, such as "5:10:60,15:60:300". Malformed triples are skipped.
+func parseRateLimitTriples(value string) [][3]int {
+	triples := make([][3]int, 0)
+	for _, part := range strings.Split(value, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ":")
+		if len(fields) != 3 {
+			continue
+		}
+		var (
+			t  [3]int
+			ok = true
+		)
+		for i, f := range fields {
+			n, err := strconv.Atoi(f)
+			if err != nil || n < 0 {
+				ok = false
+				break
+			}
+			t[i] = n
+		}
+		if ok {
+			triples = append(triples, t)
+		}
+	}
+	return triples
+}
+
+// endpointKey identifies the rate limit policy group for a URL. The API
+// applies policies per endpoint, so the host and first path segment are used,
+// e.g. "api.pathofexile.com/ladders".
+func endpointKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	segments := strings.SplitN(strings.TrimPrefix(u.Path, "/"), "/", 4)
+	n := 1
+	// The trade API publishes separate limits for each of its endpoints, such
+	// as /api/trade/search and /api/trade/fetch.
+	if len(segments) >= 3 && segments[0] == "api" && segments[1] == "trade" {
+		n = 3
+	}
+	return u.Host + "/" + strings.Join(segments[:n], "/")
+}
+
+// sleepContext pauses for the given duration, returning early with the
+// context's error if the context is canceled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
 }
 
@@ -54,5 +343,7 @@ func newRateLimiter(rateLimit, stashRateLimit float64) *ratelimiter {
 	return &ratelimiter{
 		rateLimit:      rateLimit,
 		stashRateLimit: stashRateLimit,
+		endpoints:      make(map[string]string),
+		policies:       make(map[string]*ratePolicy),
 	}
 }
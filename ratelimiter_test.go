@@ -1,6 +1,8 @@
 package poeapi
 
 import (
+	"context"
+	"net/http"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -19,7 +21,7 @@ func TestRateLimiter(t *testing.T) {
 
 	for i := 0; i < 25; i++ {
 		go func() {
-			r.Wait(false)
+			r.Wait(context.Background(), "", false)
 			atomic.AddUint32(&requestCount, 1)
 		}()
 	}
@@ -30,3 +32,130 @@ func TestRateLimiter(t *testing.T) {
 			requestCount, testDuration, int(rateLimit*testDuration))
 	}
 }
+
+func TestRateLimiterCanceledContext(t *testing.T) {
+	r := newRateLimiter(1.0, DefaultStashRateLimit)
+	if err := r.Wait(context.Background(), "", false); err != nil {
+		t.Fatalf("failed to wait for rate limiter: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := r.Wait(ctx, "", false); err != context.DeadlineExceeded {
+		t.Fatalf("failed to abort wait: expected %v, got %v",
+			context.DeadlineExceeded, err)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatal("ratelimiter waited after context deadline")
+	}
+}
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Rate-Limit-Policy", "trade-search-request-limit")
+	h.Set("X-Rate-Limit-Rules", "Ip,Account")
+	h.Set("X-Rate-Limit-Ip", "8:10:60,15:60:120")
+	h.Set("X-Rate-Limit-Ip-State", "2:10:0,3:60:0")
+	h.Set("X-Rate-Limit-Account", "3:5:60")
+	h.Set("X-Rate-Limit-Account-State", "1:5:30")
+
+	name, rules, ok := parseRateLimitHeaders(h)
+	if !ok {
+		t.Fatal("failed to parse rate limit headers")
+	}
+	if name != "trade-search-request-limit" {
+		t.Fatalf("unexpected policy name: %s", name)
+	}
+	expected := []rateLimitRule{
+		{maxHits: 8, period: 10 * time.Second, hits: 2},
+		{maxHits: 15, period: 60 * time.Second, hits: 3},
+		{maxHits: 3, period: 5 * time.Second, hits: 1, restricted: 30 * time.Second},
+	}
+	if len(rules) != len(expected) {
+		t.Fatalf("unexpected rule count: expected %d, got %d", len(expected),
+			len(rules))
+	}
+	for i := range expected {
+		if rules[i] != expected[i] {
+			t.Fatalf("unexpected rule %d: expected %+v, got %+v", i,
+				expected[i], rules[i])
+		}
+	}
+}
+
+func TestParseRateLimitHeadersWithoutPolicy(t *testing.T) {
+	if _, _, ok := parseRateLimitHeaders(http.Header{}); ok {
+		t.Fatal("failed to ignore response without rate limit headers")
+	}
+}
+
+func TestRatePolicyDelay(t *testing.T) {
+	var (
+		p   = &ratePolicy{}
+		now = time.Now()
+	)
+	p.Update([]rateLimitRule{
+		{maxHits: 2, period: 10 * time.Second, hits: 2},
+	}, now)
+
+	d := p.delay(now)
+	if d < 10*time.Second || d > 10*time.Second+policyMargin {
+		t.Fatalf("unexpected delay for full window: %v", d)
+	}
+	if d := p.delay(now.Add(11 * time.Second)); d != 0 {
+		t.Fatalf("unexpected delay after window elapsed: %v", d)
+	}
+}
+
+func TestRatePolicyRestriction(t *testing.T) {
+	var (
+		p   = &ratePolicy{}
+		now = time.Now()
+	)
+	p.Update([]rateLimitRule{
+		{maxHits: 5, period: 10 * time.Second, restricted: 60 * time.Second},
+	}, now)
+	if d := p.delay(now); d != 60*time.Second {
+		t.Fatalf("unexpected delay while restricted: %v", d)
+	}
+}
+
+func TestRateLimiterUsesPolicy(t *testing.T) {
+	c := client{
+		host:       testHost,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+	url := c.formatURL(policyEndpoint)
+	if _, err := c.getJSON(context.Background(), url); err != nil {
+		t.Fatalf("failed to get json: %v", err)
+	}
+
+	p := c.limiter.policy(endpointKey(url))
+	if p == nil {
+		t.Fatal("failed to record rate limit policy")
+	}
+	if err := c.limiter.Wait(context.Background(), endpointKey(url), false); err != nil {
+		t.Fatalf("failed to wait for policy: %v", err)
+	}
+	if hits := len(p.windows[0].hits); hits != 2 {
+		t.Fatalf("unexpected hit count: expected 2, got %d", hits)
+	}
+}
+
+func TestEndpointKey(t *testing.T) {
+	cases := map[string]string{
+		"https://api.pathofexile.com/ladders/Standard?limit=200": "api.pathofexile.com/ladders",
+		"https://api.pathofexile.com/leagues":                    "api.pathofexile.com/leagues",
+		"http://poe.ninja/api/Data/GetStats":                     "poe.ninja/api",
+		"https://www.pathofexile.com/api/trade/search/Standard":  "www.pathofexile.com/api/trade/search",
+		"https://www.pathofexile.com/api/trade/fetch/a,b?query=": "www.pathofexile.com/api/trade/fetch",
+	}
+	for url, expected := range cases {
+		if key := endpointKey(url); key != expected {
+			t.Fatalf("unexpected endpoint key for %s: expected %s, got %s",
+				url, expected, key)
+		}
+	}
+}
@@ -1,83 +1,296 @@
 package poeapi
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 )
 
-type requestFunc func(string) (string, error)
+type requestFunc func(context.Context, string) (apiResponse, error)
+
+// apiResponse is the body of a successful response, along with the headers
+// which determine how long it may be cached.
+type apiResponse struct {
+	body   string
+	header http.Header
+
+	// Set when a conditional request found that the cached response is
+	// still current, in which case body is empty.
+	notModified bool
+}
+
+// validatorsKey is the context key for the validators of a cached response,
+// which getJSON sends to revalidate it.
+type validatorsKey struct{}
+
+// withValidators returns a context which makes GET requests conditional on
+// the cached response having changed.
+func withValidators(ctx context.Context, e cacheEntry) context.Context {
+	return context.WithValue(ctx, validatorsKey{}, e)
+}
 
 // Get is a helper function which includes caching and ratelimiting for outbound
-// requests.
-func (c *client) get(url string) (string, error) {
-	return c.withCache(url, c.withRateLimit(url, c.getJSON))
+// requests. Concurrent calls for the same URL share a single request.
+func (c *client) get(ctx context.Context, url string) (string, error) {
+	request := c.withRetry(c.withRateLimit(url, c.getJSON))
+	resp, err := c.withCoalescing(ctx, url, func(ctx context.Context) (apiResponse, error) {
+		return c.withCache(ctx, url, request)
+	})
+	return resp.body, err
+}
+
+// post is like get, but sends body as a JSON POST request. Responses are
+// cached by URL and body.
+func (c *client) post(ctx context.Context, url string, body []byte) (string, error) {
+	send := func(ctx context.Context, url string) (apiResponse, error) {
+		return c.sendJSON(ctx, http.MethodPost, url, body)
+	}
+	sum := sha1.Sum(body)
+	key := url + "#" + hex.EncodeToString(sum[:])
+	request := c.withRetry(c.withRateLimit(url, send))
+	resp, err := c.withCoalescing(ctx, key, func(ctx context.Context) (apiResponse, error) {
+		return c.withCache(ctx, key, func(ctx context.Context, _ string) (apiResponse, error) {
+			return request(ctx, url)
+		})
+	})
+	return resp.body, err
 }
 
 // getJSON retrieves the given URL. It returns the JSON response as a string.
-func (c *client) getJSON(url string) (string, error) {
-	resp, err := c.httpClient.Get(url)
+func (c *client) getJSON(ctx context.Context, url string) (apiResponse, error) {
+	return c.sendJSON(ctx, http.MethodGet, url, nil)
+}
+
+// sendJSON sends a request with an optional JSON body to the given URL. It
+// returns the JSON response as a string.
+func (c *client) sendJSON(ctx context.Context, method, url string, body []byte) (apiResponse, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, r)
 	if err != nil {
+		return apiResponse{}, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	conditional := false
+	if e, ok := ctx.Value(validatorsKey{}).(cacheEntry); ok && method == http.MethodGet {
+		if e.ETag != "" {
+			req.Header.Set("If-None-Match", e.ETag)
+		}
+		if e.LastModified != "" {
+			req.Header.Set("If-Modified-Since", e.LastModified)
+		}
+		conditional = e.revalidatable()
+	}
+	if err := c.authorize(ctx, req); err != nil {
+		return apiResponse{}, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		// Report cancellation directly rather than wrapped in a *url.Error.
+		if ctx.Err() != nil {
+			return apiResponse{}, ctx.Err()
+		}
 		// An error is returned if the Client's CheckRedirect function fails or
 		// if there was an HTTP protocol error. A non-2xx response doesn't cause
 		// an error.
-		return "", err
+		return apiResponse{}, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return "", parseError(resp.StatusCode)
-	}
+	// Rate limit headers are sent with failed responses too, and are most
+	// important when we have been rate limited.
+	c.limiter.Update(endpointKey(url), resp.Header)
 
 	b, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return "", err
+		if ctx.Err() != nil {
+			return apiResponse{}, ctx.Err()
+		}
+		return apiResponse{}, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && conditional {
+		return apiResponse{header: resp.Header, notModified: true}, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return apiResponse{}, newAPIError(resp, b)
 	}
-	return string(b), nil
+	return apiResponse{body: string(b), header: resp.Header}, nil
 }
 
-func (c *client) withCache(url string, fn requestFunc) (string, error) {
+// withCache serves responses from the cache when possible, and caches new
+// responses for as long as their Cache-Control or Expires headers allow.
+// Responses without either header are cached for the endpoint's default TTL.
+//
+// Responses with an ETag or Last-Modified header are kept after they become
+// stale, and are revalidated with a conditional request. A 304 Not Modified
+// response refreshes the cached response without transferring it again.
+func (c *client) withCache(ctx context.Context, url string, fn requestFunc) (apiResponse, error) {
 	if !c.useCache {
-		return fn(url)
+		return fn(ctx, url)
 	}
 
 	// Disable caching for stash endpoint.
 	if strings.HasPrefix(url, c.formatURL(stashTabsEndpoint)) {
-		return fn(url)
+		return fn(ctx, url)
 	}
 
+	var stale *cacheEntry
 	if cached, err := c.cache.Get(url); err == nil {
-		return cached, nil
+		e := decodeCacheEntry(cached)
+		if e.fresh(time.Now()) || !e.revalidatable() {
+			return apiResponse{body: e.Body}, nil
+		}
+		stale = &e
+		ctx = withValidators(ctx, e)
 	}
 
-	resp, err := fn(url)
+	resp, err := fn(ctx, url)
 	if err != nil {
-		return "", err
+		return apiResponse{}, err
+	}
+	if resp.notModified {
+		resp.body = stale.Body
+		// A 304 response need not repeat the validators.
+		resp.header = resp.header.Clone()
+		if resp.header.Get("ETag") == "" && stale.ETag != "" {
+			resp.header.Set("ETag", stale.ETag)
+		}
+		if resp.header.Get("Last-Modified") == "" && stale.LastModified != "" {
+			resp.header.Set("Last-Modified", stale.LastModified)
+		}
 	}
 
-	c.cache.Set(url, resp)
+	c.store(url, resp)
 	return resp, nil
 }
 
+// store caches a response according to its headers. A failure to cache the
+// response does not affect the request, so errors are ignored.
+func (c *client) store(url string, resp apiResponse) {
+	if cacheNoStore(resp.header) {
+		return
+	}
+	now := time.Now()
+	e := cacheEntry{
+		ETag:         resp.header.Get("ETag"),
+		LastModified: resp.header.Get("Last-Modified"),
+		Body:         resp.body,
+	}
+
+	ttl, ok := cacheTTL(resp.header, now)
+	if !ok {
+		ttl = c.defaultCacheTTL(url)
+	} else if ttl == 0 {
+		if !e.revalidatable() {
+			// The response must not be cached.
+			return
+		}
+		// The response may be stored, but must be revalidated before use.
+		e.Expires = now.UnixNano()
+		c.cache.Set(url, e.encode(), 0)
+		return
+	}
+
+	if !e.revalidatable() {
+		// Without validators, a stale response is useless, so let the cache
+		// expire it.
+		c.cache.Set(url, resp.body, ttl)
+		return
+	}
+	// Keep stale responses until they are evicted, so that they can be
+	// revalidated.
+	if ttl > 0 {
+		e.Expires = now.Add(ttl).UnixNano()
+	}
+	c.cache.Set(url, e.encode(), 0)
+}
+
+// defaultCacheTTL returns the TTL for responses from the given URL which do
+// not specify their own. The longest matching endpoint in CacheTTLs is used,
+// falling back to CacheTTL.
+func (c *client) defaultCacheTTL(url string) time.Duration {
+	var (
+		path    = cachePath(url)
+		ttl     = c.cacheTTL
+		longest = -1
+	)
+	for endpoint, d := range c.cacheTTLs {
+		if len(endpoint) <= longest {
+			continue
+		}
+		if path == endpoint || strings.HasPrefix(path, strings.TrimSuffix(endpoint, "/")+"/") {
+			ttl, longest = d, len(endpoint)
+		}
+	}
+	return ttl
+}
+
+// Invalidate removes cached responses whose URL path begins with prefix, and
+// returns the number removed. It returns 0 when the client's Cache does not
+// implement CacheInvalidator.
+func (c *client) Invalidate(prefix string) int {
+	if inv, ok := c.cache.(CacheInvalidator); ok {
+		return inv.Invalidate(prefix)
+	}
+	return 0
+}
+
+// CacheStats reports the activity of the client's Cache, which is empty when
+// the Cache does not implement CacheStatsReporter. Coalesced counts requests
+// which were served by sharing one in-flight request rather than sending their
+// own.
+func (c *client) CacheStats() CacheStats {
+	var stats CacheStats
+	if r, ok := c.cache.(CacheStatsReporter); ok {
+		stats = r.Stats()
+	}
+	if c.flights != nil {
+		stats.Coalesced = c.flights.Coalesced()
+	}
+	return stats
+}
+
+// withRateLimit wraps fn so that it blocks until the rate limiter allows the
+// request to be sent. Waiting is aborted when the context is canceled.
 func (c *client) withRateLimit(url string, fn requestFunc) requestFunc {
-	if strings.HasPrefix(url, c.formatURL(stashTabsEndpoint)) {
-		c.limiter.Wait(true)
-		return fn
+	var (
+		endpoint = endpointKey(url)
+		stash    = strings.HasPrefix(url, c.formatURL(stashTabsEndpoint))
+	)
+	return func(ctx context.Context, url string) (apiResponse, error) {
+		if err := c.limiter.Wait(ctx, endpoint, stash); err != nil {
+			return apiResponse{}, err
+		}
+		return fn(ctx, url)
 	}
-	c.limiter.Wait(false)
-	return fn
 }
 
+// parseError returns the sentinel error for a non-200 status code.
 func parseError(statusCode int) error {
+	if statusCode >= http.StatusInternalServerError && statusCode <= 599 {
+		return ErrServerFailure
+	}
 	switch statusCode {
 	case http.StatusBadRequest:
 		return ErrBadRequest
+	case http.StatusForbidden:
+		return ErrForbidden
 	case http.StatusNotFound:
 		return ErrNotFound
 	case http.StatusTooManyRequests:
 		return ErrRateLimited
-	case http.StatusInternalServerError:
-		return ErrServerFailure
 	default:
 		return ErrUnknownFailure
 	}
@@ -1,9 +1,12 @@
 package poeapi
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestGetJSON(t *testing.T) {
@@ -15,7 +18,7 @@ func TestGetJSON(t *testing.T) {
 		}
 		url = c.formatURL(leaguesEndpoint)
 	)
-	_, err := c.getJSON(url)
+	_, err := c.getJSON(context.Background(), url)
 	if err != nil {
 		t.Fatalf("failed to get json: %v", err)
 	}
@@ -30,12 +33,28 @@ func TestGetStashsJSON(t *testing.T) {
 		}
 		url = c.formatURL(stashTabsEndpoint)
 	)
-	_, err := c.getJSON(url)
+	_, err := c.getJSON(context.Background(), url)
 	if err != nil {
 		t.Fatalf("failed to get stash json: %v", err)
 	}
 }
 
+func TestGetJSONWithCanceledContext(t *testing.T) {
+	var (
+		c = client{
+			host:       testHost,
+			limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+			httpClient: testClient,
+		}
+		url         = c.formatURL(leaguesEndpoint)
+		ctx, cancel = context.WithCancel(context.Background())
+	)
+	cancel()
+	if _, err := c.getJSON(ctx, url); err != context.Canceled {
+		t.Fatalf("failed to detect canceled context: %v", err)
+	}
+}
+
 func TestGetJSONWithInvalidProtocol(t *testing.T) {
 	var (
 		c = client{
@@ -45,7 +64,7 @@ func TestGetJSONWithInvalidProtocol(t *testing.T) {
 		}
 		url = "htps://127.0.0.1:8000"
 	)
-	_, err := c.getJSON(url)
+	_, err := c.getJSON(context.Background(), url)
 	if err == nil {
 		t.Fatal("failed to detect invalid http protocol")
 	}
@@ -71,7 +90,7 @@ func TestGetJSONRateLimit(t *testing.T) {
 	for i := 0; i < 50; i++ {
 		wg.Add(1)
 		go func() {
-			_, err := c.getJSON(url)
+			_, err := c.getJSON(context.Background(), url)
 			errs.lock.Lock()
 			errs.set = append(errs.set, err)
 			errs.lock.Unlock()
@@ -81,7 +100,7 @@ func TestGetJSONRateLimit(t *testing.T) {
 	wg.Wait()
 	rateLimited := false
 	for _, e := range errs.set {
-		if e == ErrRateLimited {
+		if errors.Is(e, ErrRateLimited) {
 			rateLimited = true
 			break
 		}
@@ -98,8 +117,8 @@ func TestHandleServerError(t *testing.T) {
 		httpClient: testClient,
 	}
 
-	_, err := c.getJSON(c.formatURL(failureEndpoint))
-	if err != ErrServerFailure {
+	_, err := c.getJSON(context.Background(), c.formatURL(failureEndpoint))
+	if !errors.Is(err, ErrServerFailure) {
 		t.Fatal("failed to handle server error")
 	}
 }
@@ -112,9 +131,11 @@ func TestWithRateLimit(t *testing.T) {
 			httpClient: testClient,
 		}
 		url = "https://127.0.0.1:8000"
-		fn  = func(s string) (string, error) { return s, nil }
+		fn  = func(ctx context.Context, s string) (apiResponse, error) { return apiResponse{body: s}, nil }
 	)
-	_ = c.withRateLimit(url, fn)
+	if _, err := c.withRateLimit(url, fn)(context.Background(), url); err != nil {
+		t.Fatalf("failed to wait for rate limit: %v", err)
+	}
 }
 
 func TestWithStashRateLimit(t *testing.T) {
@@ -126,9 +147,11 @@ func TestWithStashRateLimit(t *testing.T) {
 			httpClient: testClient,
 		}
 		url = "https://127.0.0.1:8000/public-stash-tabs"
-		fn  = func(s string) (string, error) { return s, nil }
+		fn  = func(ctx context.Context, s string) (apiResponse, error) { return apiResponse{body: s}, nil }
 	)
-	_ = c.withRateLimit(url, fn)
+	if _, err := c.withRateLimit(url, fn)(context.Background(), url); err != nil {
+		t.Fatalf("failed to wait for rate limit: %v", err)
+	}
 }
 
 func TestParseCodeBadRequest(t *testing.T) {
@@ -137,6 +160,12 @@ func TestParseCodeBadRequest(t *testing.T) {
 	}
 }
 
+func TestParseCodeForbidden(t *testing.T) {
+	if err := parseError(http.StatusForbidden); err != ErrForbidden {
+		t.Fatal("failed to detect forbidden request")
+	}
+}
+
 func TestParseCodeUnknownFailure(t *testing.T) {
 	if err := parseError(http.StatusTeapot); err != ErrUnknownFailure {
 		t.Fatal("failed to detect unknown error")
@@ -150,17 +179,17 @@ func TestCacheHelperWithStashURL(t *testing.T) {
 			useCache: true,
 		}
 		url = c.formatURL(stashTabsEndpoint)
-		fn  = func(s string) (string, error) {
-			return "", nil
+		fn  = func(ctx context.Context, s string) (apiResponse, error) {
+			return apiResponse{}, nil
 		}
 	)
-	if _, err := c.withCache(url, fn); err != nil {
+	if _, err := c.withCache(context.Background(), url, fn); err != nil {
 		t.Fatal("failed to bypass cache in decorated function")
 	}
 }
 
 func TestCacheHelperWithErrorResult(t *testing.T) {
-	cache, err := newResponseCache(10)
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
 	if err != nil {
 		t.Fatalf("failed to create cache: %v", err)
 	}
@@ -171,11 +200,186 @@ func TestCacheHelperWithErrorResult(t *testing.T) {
 			cache:    cache,
 		}
 		url = c.formatURL(leaguesEndpoint)
-		fn  = func(s string) (string, error) {
-			return "", ErrUnknownFailure
+		fn  = func(ctx context.Context, s string) (apiResponse, error) {
+			return apiResponse{}, ErrUnknownFailure
 		}
 	)
-	if _, err := c.withCache(url, fn); err != ErrUnknownFailure {
+	if _, err := c.withCache(context.Background(), url, fn); err != ErrUnknownFailure {
 		t.Fatal("failed to detect error in decorated function")
 	}
 }
+
+func TestCacheHelperWithCacheHeaders(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	var (
+		c = client{
+			host:     testHost,
+			useCache: true,
+			cache:    cache,
+		}
+		calls  int
+		header http.Header
+		fn     = func(ctx context.Context, s string) (apiResponse, error) {
+			calls++
+			return apiResponse{body: s, header: header}, nil
+		}
+		url = c.formatURL(leaguesEndpoint)
+	)
+
+	header = http.Header{"Cache-Control": {"no-store"}}
+	c.withCache(context.Background(), url, fn)
+	c.withCache(context.Background(), url, fn)
+	if calls != 2 {
+		t.Fatalf("cached response marked no-store: %d calls", calls)
+	}
+
+	header = http.Header{"Cache-Control": {"max-age=60"}}
+	c.withCache(context.Background(), url, fn)
+	resp, err := c.withCache(context.Background(), url, fn)
+	if err != nil || resp.body != url {
+		t.Fatalf("unexpected cached response: %q, %v", resp.body, err)
+	}
+	if calls != 3 {
+		t.Fatalf("failed to cache response: %d calls", calls)
+	}
+	if n := c.Invalidate(leaguesEndpoint); n != 1 {
+		t.Fatalf("unexpected invalidation count: expected 1, got %d", n)
+	}
+}
+
+func TestDefaultCacheTTL(t *testing.T) {
+	c := client{
+		host:     testHost,
+		cacheTTL: time.Hour,
+		cacheTTLs: map[string]time.Duration{
+			"/leagues":          time.Minute,
+			"/leagues/Standard": time.Second,
+			"/api/trade/":       2 * time.Minute,
+		},
+	}
+	cases := map[string]time.Duration{
+		c.formatURL("/leagues"):                   time.Minute,
+		c.formatURL("/leagues?type=main"):         time.Minute,
+		c.formatURL("/leagues/Hardcore"):          time.Minute,
+		c.formatURL("/leagues/Standard"):          time.Second,
+		c.formatURL("/leagues-archive"):           time.Hour,
+		c.formatURL("/ladders/Standard"):          time.Hour,
+		c.formatURL("/api/trade/search/Standard"): 2 * time.Minute,
+	}
+	for url, expected := range cases {
+		if ttl := c.defaultCacheTTL(url); ttl != expected {
+			t.Fatalf("unexpected default ttl for %s: expected %v, got %v",
+				url, expected, ttl)
+		}
+	}
+}
+
+func TestInvalidateWithoutCache(t *testing.T) {
+	c := client{host: testHost}
+	if n := c.Invalidate(""); n != 0 {
+		t.Fatalf("unexpected invalidation count: %d", n)
+	}
+}
+
+func TestConditionalRevalidation(t *testing.T) {
+	for _, validator := range []string{"etag", "modified", "both"} {
+		cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
+		if err != nil {
+			t.Fatalf("failed to create cache: %v", err)
+		}
+		var statuses []int
+		c := client{
+			host:     testHost,
+			useCache: true,
+			cache:    cache,
+			limiter:  newRateLimiter(UnlimitedRate, UnlimitedRate),
+			httpClient: &http.Client{
+				Timeout: testTimeout,
+				Transport: RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+					resp, err := http.DefaultTransport.RoundTrip(req)
+					if err == nil {
+						statuses = append(statuses, resp.StatusCode)
+					}
+					return resp, err
+				}),
+			},
+		}
+
+		url := c.formatURL(conditionalPath + "?validator=" + validator)
+		for i := 0; i < 3; i++ {
+			body, err := c.get(context.Background(), url)
+			if err != nil {
+				t.Fatalf("failed to get conditional response: %v", err)
+			}
+			if body != `{"conditional":true}` {
+				t.Fatalf("unexpected conditional response: %s", body)
+			}
+		}
+		expected := []int{http.StatusOK, http.StatusNotModified, http.StatusNotModified}
+		if len(statuses) != len(expected) {
+			t.Fatalf("unexpected request count for %s: %v", validator, statuses)
+		}
+		for i := range expected {
+			if statuses[i] != expected[i] {
+				t.Fatalf("unexpected statuses for %s: %v", validator, statuses)
+			}
+		}
+	}
+}
+
+func TestGetJSONWithEmptyValidators(t *testing.T) {
+	c := client{
+		host:       testHost,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+	ctx := withValidators(context.Background(), cacheEntry{})
+	if _, err := c.getJSON(ctx, c.formatURL(conditionalPath)); err != nil {
+		t.Fatalf("failed to get unconditional response: %v", err)
+	}
+}
+
+func TestStoreRevalidatableResponse(t *testing.T) {
+	cache, err := newResponseCache(MemoryCacheOptions{MaxEntries: 10})
+	if err != nil {
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	c := client{host: testHost, useCache: true, cache: cache}
+	url := c.formatURL(leaguesEndpoint)
+
+	c.store(url, apiResponse{
+		body:   "[]",
+		header: http.Header{"Cache-Control": {"max-age=60"}, "Etag": {`"v2"`}},
+	})
+	cached, err := cache.Get(url)
+	if err != nil {
+		t.Fatalf("failed to cache response: %v", err)
+	}
+	e := decodeCacheEntry(cached)
+	if e.Body != "[]" || e.ETag != `"v2"` || !e.fresh(time.Now()) ||
+		e.fresh(time.Now().Add(time.Minute)) {
+		t.Fatalf("unexpected cache entry: %+v", e)
+	}
+
+	c.store(url, apiResponse{
+		body:   "[]",
+		header: http.Header{"Cache-Control": {"no-store"}, "Etag": {`"v3"`}},
+	})
+	if cached, _ := cache.Get(url); decodeCacheEntry(cached).ETag != `"v2"` {
+		t.Fatal("stored response marked no-store")
+	}
+}
+
+func TestDecodeCacheEntry(t *testing.T) {
+	e := cacheEntry{ETag: `"v1"`, Expires: 42, Body: "{\n}"}
+	if decoded := decodeCacheEntry(e.encode()); decoded != e {
+		t.Fatalf("unexpected decoded entry: %+v", decoded)
+	}
+	if decoded := decodeCacheEntry(`{"raw":true}`); decoded.Body != `{"raw":true}` ||
+		decoded.revalidatable() || !decoded.fresh(time.Now()) {
+		t.Fatalf("unexpected decoded raw entry: %+v", decoded)
+	}
+}
@@ -0,0 +1,139 @@
+package poeapi
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts sets the number of times a request is attempted
+	// before its error is returned, including the first attempt.
+	DefaultMaxAttempts = 3
+
+	// DefaultBaseBackoff sets the delay before the first retry. The delay
+	// doubles with each subsequent retry.
+	DefaultBaseBackoff = 500 * time.Millisecond
+
+	// DefaultMaxBackoff caps the delay between retries. It does not apply to
+	// delays requested by the API with a Retry-After header.
+	DefaultMaxBackoff = 10 * time.Second
+
+	// DefaultJitter randomizes each backoff delay by up to 20% so that
+	// concurrent callers do not retry in lockstep.
+	DefaultJitter = 0.2
+)
+
+// RetryPolicy controls how failed requests are retried. The zero value
+// disables retries.
+type RetryPolicy struct {
+	// The number of times a request is attempted before its error is
+	// returned, including the first attempt. Values of 0 or 1 disable retries.
+	MaxAttempts int
+
+	// The delay before the first retry. The delay doubles with each retry.
+	BaseBackoff time.Duration
+
+	// The maximum delay between retries. When the API responds with a
+	// Retry-After header, that delay is used instead, even if it is longer.
+	MaxBackoff time.Duration
+
+	// The fraction of each backoff delay, between 0 and 1, which is randomized.
+	Jitter float64
+
+	// Retryable reports whether a request which failed with the given error
+	// should be retried. When nil, IsRetryable is used.
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy retries rate limited requests, server failures, and
+// network timeouts up to twice.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: DefaultMaxAttempts,
+	BaseBackoff: DefaultBaseBackoff,
+	MaxBackoff:  DefaultMaxBackoff,
+	Jitter:      DefaultJitter,
+}
+
+// IsRetryable reports whether err is a transient failure: a rate limited
+// response, a 5xx response, or a network timeout. Context cancellation is never
+// retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimited) || errors.Is(err, ErrServerFailure) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// backoff returns the delay before the given retry, where retry 1 is the
+// first retry. A Retry-After delay from the API takes precedence.
+func (p RetryPolicy) backoff(retry int, err error) time.Duration {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter
+	}
+
+	d := p.BaseBackoff
+	for i := 1; i < retry; i++ {
+		d *= 2
+		if p.MaxBackoff > 0 && d >= p.MaxBackoff {
+			break
+		}
+	}
+	if p.MaxBackoff > 0 && d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if p.Jitter > 0 {
+		d -= time.Duration(rand.Float64() * p.Jitter * float64(d))
+	}
+	return d
+}
+
+func (p RetryPolicy) retryable(err error) bool {
+	if p.Retryable != nil {
+		return p.Retryable(err)
+	}
+	return IsRetryable(err)
+}
+
+// withRetry wraps fn so that transient failures are retried according to the
+// client's retry policy. Each attempt passes through fn, so wrapping a rate
+// limited function ensures that retries wait their turn with other requests.
+func (c *client) withRetry(fn requestFunc) requestFunc {
+	return func(ctx context.Context, url string) (apiResponse, error) {
+		for attempt := 1; ; attempt++ {
+			resp, err := fn(ctx, url)
+			if err == nil || attempt >= c.retry.MaxAttempts || !c.retry.retryable(err) {
+				return resp, err
+			}
+			if err := sleepContext(ctx, c.retry.backoff(attempt, err)); err != nil {
+				return apiResponse{}, err
+			}
+		}
+	}
+}
+
+func validateRetryPolicy(p RetryPolicy) error {
+	if p.MaxAttempts < 0 {
+		return ErrInvalidRetryPolicy
+	}
+	if p.BaseBackoff < 0 || p.MaxBackoff < 0 {
+		return ErrInvalidRetryPolicy
+	}
+	if p.MaxBackoff > 0 && p.MaxBackoff < p.BaseBackoff {
+		return ErrInvalidRetryPolicy
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		return ErrInvalidRetryPolicy
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package poeapi
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWithRetry(t *testing.T) {
+	var (
+		c = client{
+			retry: RetryPolicy{
+				MaxAttempts: 3,
+				BaseBackoff: time.Millisecond,
+			},
+		}
+		calls int
+		fn    = func(ctx context.Context, s string) (apiResponse, error) {
+			calls++
+			if calls < 3 {
+				return apiResponse{}, ErrServerFailure
+			}
+			return apiResponse{body: "ok"}, nil
+		}
+	)
+	resp, err := c.withRetry(fn)(context.Background(), "")
+	if err != nil {
+		t.Fatalf("failed to retry request: %v", err)
+	}
+	if resp.body != "ok" || calls != 3 {
+		t.Fatalf("unexpected retry result: %s after %d calls", resp.body, calls)
+	}
+}
+
+func TestWithRetryExhausted(t *testing.T) {
+	var (
+		c = client{
+			retry: RetryPolicy{
+				MaxAttempts: 2,
+				BaseBackoff: time.Millisecond,
+			},
+		}
+		calls int
+		fn    = func(ctx context.Context, s string) (apiResponse, error) {
+			calls++
+			return apiResponse{}, ErrRateLimited
+		}
+	)
+	if _, err := c.withRetry(fn)(context.Background(), ""); err != ErrRateLimited {
+		t.Fatalf("failed to return final error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("unexpected attempt count: expected 2, got %d", calls)
+	}
+}
+
+func TestWithRetryNotRetryable(t *testing.T) {
+	var (
+		c = client{
+			retry: DefaultRetryPolicy,
+		}
+		calls int
+		fn    = func(ctx context.Context, s string) (apiResponse, error) {
+			calls++
+			return apiResponse{}, ErrNotFound
+		}
+	)
+	if _, err := c.withRetry(fn)(context.Background(), ""); err != ErrNotFound {
+		t.Fatalf("failed to return non-retryable error: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("retried non-retryable error %d times", calls-1)
+	}
+}
+
+func TestWithRetryCanceledContext(t *testing.T) {
+	var (
+		c = client{
+			retry: RetryPolicy{
+				MaxAttempts: 3,
+				BaseBackoff: time.Hour,
+			},
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+		fn          = func(ctx context.Context, s string) (apiResponse, error) {
+			cancel()
+			return apiResponse{}, ErrServerFailure
+		}
+	)
+	if _, err := c.withRetry(fn)(ctx, ""); err != context.Canceled {
+		t.Fatalf("failed to abort retry backoff: %v", err)
+	}
+}
+
+func TestRetryBackoff(t *testing.T) {
+	p := RetryPolicy{
+		BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff:  300 * time.Millisecond,
+	}
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		300 * time.Millisecond,
+		300 * time.Millisecond,
+	}
+	for i, e := range expected {
+		if d := p.backoff(i+1, ErrServerFailure); d != e {
+			t.Fatalf("unexpected backoff for retry %d: expected %v, got %v",
+				i+1, e, d)
+		}
+	}
+}
+
+func TestRetryBackoffJitter(t *testing.T) {
+	p := RetryPolicy{BaseBackoff: 100 * time.Millisecond, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		d := p.backoff(1, ErrServerFailure)
+		if d < 50*time.Millisecond || d > 100*time.Millisecond {
+			t.Fatalf("jittered backoff out of range: %v", d)
+		}
+	}
+}
+
+func TestRetryBackoffRetryAfter(t *testing.T) {
+	var (
+		p   = DefaultRetryPolicy
+		err = &APIError{RetryAfter: time.Minute, sentinel: ErrRateLimited}
+	)
+	if d := p.backoff(1, err); d != time.Minute {
+		t.Fatalf("failed to honor retry after: %v", d)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	cases := map[error]bool{
+		ErrRateLimited:                        true,
+		ErrServerFailure:                      true,
+		ErrNotFound:                           false,
+		context.Canceled:                      false,
+		context.DeadlineExceeded:              false,
+		&APIError{sentinel: ErrServerFailure}: true,
+	}
+	for err, expected := range cases {
+		if IsRetryable(err) != expected {
+			t.Fatalf("unexpected retryable result for %v", err)
+		}
+	}
+}
+
+func TestValidateRetryPolicy(t *testing.T) {
+	invalid := []RetryPolicy{
+		{MaxAttempts: -1},
+		{BaseBackoff: -1},
+		{BaseBackoff: time.Second, MaxBackoff: time.Millisecond},
+		{Jitter: 1.5},
+	}
+	for _, p := range invalid {
+		if err := validateRetryPolicy(p); !errors.Is(err, ErrInvalidRetryPolicy) {
+			t.Fatalf("failed to detect invalid retry policy: %+v", p)
+		}
+	}
+	if err := validateRetryPolicy(DefaultRetryPolicy); err != nil {
+		t.Fatalf("failed to validate default retry policy: %v", err)
+	}
+}
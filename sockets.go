@@ -0,0 +1,172 @@
+package poeapi
+
+import (
+	"strings"
+)
+
+// Socket colours, as found in Socket.Colour.
+const (
+	SocketRed   = "R"
+	SocketGreen = "G"
+	SocketBlue  = "B"
+	SocketWhite = "W"
+	SocketAbyss = "A"
+	SocketDelve = "DV"
+)
+
+// socketAttributes maps socket colours to the attribute which the API reports
+// for them.
+var socketAttributes = map[string]string{
+	SocketRed:   "S",
+	SocketGreen: "D",
+	SocketBlue:  "I",
+	SocketWhite: "G",
+	SocketAbyss: "A",
+	SocketDelve: "DV",
+}
+
+// SocketColour returns the colour of the socket. Older listings omit the
+// colour, in which case it is derived from the socket attribute.
+func (s Socket) SocketColour() string {
+	if s.Colour != "" {
+		return s.Colour
+	}
+	for colour, attr := range socketAttributes {
+		if attr == s.Attribute {
+			return colour
+		}
+	}
+	return ""
+}
+
+// SocketColours counts the sockets of each colour on an item.
+type SocketColours struct {
+	Red   int
+	Green int
+	Blue  int
+	White int
+	Abyss int
+	Delve int
+}
+
+// Total returns the total number of sockets.
+func (c SocketColours) Total() int {
+	return c.Red + c.Green + c.Blue + c.White + c.Abyss + c.Delve
+}
+
+// Satisfies reports whether sockets with these colours can meet the colour
+// requirement in r. White sockets count towards any red, green, or blue
+// requirement.
+func (c SocketColours) Satisfies(r SocketColours) bool {
+	if c.Abyss < r.Abyss || c.Delve < r.Delve || c.White < r.White {
+		return false
+	}
+	spare := c.White - r.White
+	for _, pair := range [][2]int{{c.Red, r.Red}, {c.Green, r.Green}, {c.Blue, r.Blue}} {
+		if missing := pair[1] - pair[0]; missing > 0 {
+			spare -= missing
+		}
+	}
+	return spare >= 0
+}
+
+func (c *SocketColours) add(colour string) {
+	switch colour {
+	case SocketRed:
+		c.Red++
+	case SocketGreen:
+		c.Green++
+	case SocketBlue:
+		c.Blue++
+	case SocketWhite:
+		c.White++
+	case SocketAbyss:
+		c.Abyss++
+	case SocketDelve:
+		c.Delve++
+	}
+}
+
+// SocketColours counts the sockets of each colour on the item.
+func (i Item) SocketColours() SocketColours {
+	var c SocketColours
+	for _, s := range i.Sockets {
+		c.add(s.SocketColour())
+	}
+	return c
+}
+
+// LinkGroups returns the item's sockets divided into groups of linked
+// sockets, in the order they appear on the item.
+func (i Item) LinkGroups() [][]Socket {
+	var groups [][]Socket
+	index := make(map[int64]int)
+	for _, s := range i.Sockets {
+		n, ok := index[s.Group]
+		if !ok {
+			n = len(groups)
+			index[s.Group] = n
+			groups = append(groups, nil)
+		}
+		groups[n] = append(groups[n], s)
+	}
+	return groups
+}
+
+// MaxLinks returns the size of the item's largest group of linked sockets.
+func (i Item) MaxLinks() int {
+	max := 0
+	for _, g := range i.LinkGroups() {
+		if len(g) > max {
+			max = len(g)
+		}
+	}
+	return max
+}
+
+// SocketString renders the item's sockets in the familiar format used by the
+// game and trade sites, where linked sockets are joined by dashes and groups
+// are separated by spaces, such as "R-G-B B-W".
+func (i Item) SocketString() string {
+	return FormatSockets(i.Sockets)
+}
+
+// FormatSockets renders sockets in the format described by SocketString.
+func FormatSockets(sockets []Socket) string {
+	var b strings.Builder
+	for n, s := range sockets {
+		if n > 0 {
+			if s.Group == sockets[n-1].Group {
+				b.WriteByte('-')
+			} else {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(s.SocketColour())
+	}
+	return b.String()
+}
+
+// ParseSockets parses a socket string such as "R-G-B B-W" into sockets. Group
+// numbers are assigned in order, starting from zero.
+func ParseSockets(s string) ([]Socket, error) {
+	var sockets []Socket
+	for group, links := range strings.Fields(s) {
+		for _, colour := range strings.Split(links, "-") {
+			colour = strings.ToUpper(colour)
+			attr, ok := socketAttributes[colour]
+			if !ok {
+				return nil, ErrInvalidSockets
+			}
+			sockets = append(sockets, Socket{
+				Attribute: attr,
+				Group:     int64(group),
+				Colour:    colour,
+			})
+		}
+	}
+	if len(sockets) == 0 {
+		return nil, ErrInvalidSockets
+	}
+	return sockets, nil
+}
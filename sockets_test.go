@@ -0,0 +1,90 @@
+package poeapi
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLinkGroups(t *testing.T) {
+	sockets, err := ParseSockets("R-G-B B-W")
+	if err != nil {
+		t.Fatalf("failed to parse sockets: %v", err)
+	}
+	item := Item{Sockets: sockets}
+
+	groups := item.LinkGroups()
+	if len(groups) != 2 || len(groups[0]) != 3 || len(groups[1]) != 2 {
+		t.Fatalf("failed to group sockets: %+v", groups)
+	}
+	if item.MaxLinks() != 3 {
+		t.Fatalf("failed to count links. expected 3, got %d", item.MaxLinks())
+	}
+	if (Item{}).MaxLinks() != 0 {
+		t.Fatal("failed to count links on an item without sockets")
+	}
+}
+
+func TestSocketColours(t *testing.T) {
+	item := Item{Sockets: []Socket{
+		{Attribute: "S", Group: 0},
+		{Attribute: "D", Group: 0, Colour: "G"},
+		{Attribute: "G", Group: 0, Colour: "W"},
+		{Attribute: "A", Group: 1, Colour: "A"},
+	}}
+	expected := SocketColours{Red: 1, Green: 1, White: 1, Abyss: 1}
+	if c := item.SocketColours(); c != expected {
+		t.Fatalf("failed to count socket colours. expected %+v, got %+v",
+			expected, c)
+	}
+	if item.SocketColours().Total() != 4 {
+		t.Fatal("failed to count total sockets")
+	}
+	if !item.SocketColours().Satisfies(SocketColours{Green: 2}) {
+		t.Fatal("failed to use white socket for colour requirement")
+	}
+	if item.SocketColours().Satisfies(SocketColours{Blue: 1, Red: 2}) {
+		t.Fatal("failed to reject unmet colour requirement")
+	}
+}
+
+func TestFormatSockets(t *testing.T) {
+	for _, s := range []string{"R-G-B B-W", "R", "A DV", "R-R-R-R-R-R"} {
+		sockets, err := ParseSockets(s)
+		if err != nil {
+			t.Fatalf("failed to parse sockets %q: %v", s, err)
+		}
+		if out := (Item{Sockets: sockets}).SocketString(); out != s {
+			t.Fatalf("failed to format sockets. expected %q, got %q", s, out)
+		}
+	}
+}
+
+func TestParseInvalidSockets(t *testing.T) {
+	for _, s := range []string{"", "R-X", "R--G", "R-G-"} {
+		if _, err := ParseSockets(s); !errors.Is(err, ErrInvalidSockets) {
+			t.Fatalf("failed to detect invalid sockets %q: %v", s, err)
+		}
+	}
+}
+
+func TestSocketStringFromFixture(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+	for _, stash := range stashes.Stashes {
+		for _, item := range stash.Items {
+			if item.Name == "Command of the Pit" {
+				if s := item.SocketString(); s != "W-R A A" {
+					t.Fatalf("failed to format fixture sockets. got %q", s)
+				}
+				return
+			}
+		}
+	}
+	t.Fatal("failed to find gloves in fixture")
+}
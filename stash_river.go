@@ -0,0 +1,285 @@
+package poeapi
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRiverPollInterval sets the time to wait before requesting the
+	// next change ID again once the river has caught up with the latest
+	// stashes.
+	DefaultRiverPollInterval = 2 * time.Second
+
+	// DefaultRiverMaxBackoff caps the delay between retries of failed stash
+	// requests.
+	DefaultRiverMaxBackoff = time.Minute
+)
+
+// Checkpointer persists the change ID from which a StashRiver resumes.
+// Implementations must be safe for concurrent use.
+type Checkpointer interface {
+	// Load returns the saved change ID, or an empty string if none has been
+	// saved.
+	Load() (string, error)
+
+	// Save records the change ID from which to resume.
+	Save(changeID string) error
+}
+
+// StashRiverOptions contains settings for a StashRiver. All fields are
+// optional.
+type StashRiverOptions struct {
+	// The change ID to start from when the checkpointer has no saved change
+	// ID. When empty, the latest change ID is retrieved from poe.ninja.
+	StartID string
+
+	// Persists the change ID following the last committed batch. A saved
+	// change ID takes precedence over StartID, so that restarts resume where
+	// they left off.
+	Checkpointer Checkpointer
+
+	// Time to wait once caught up with the latest stashes. Defaults to
+	// DefaultRiverPollInterval.
+	PollInterval time.Duration
+
+	// Maximum delay between retries of transient failures. Defaults to
+	// DefaultRiverMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// StashBatch is a single response from the stash API.
+type StashBatch struct {
+	// The change ID which was requested.
+	ChangeID string
+
+	// The change ID from which the next batch will be requested.
+	NextChangeID string
+
+	Stashes []Stash
+}
+
+// StashRiver consumes the public stash API as a stream of batches. Batches are
+// delivered in order on an unbuffered channel. The consumer calls Commit once
+// it has processed a batch, which saves the batch's NextChangeID with the
+// Checkpointer. After a restart, delivery resumes with the batch following the
+// last committed one, so batches which were received but not committed are
+// delivered again.
+type StashRiver struct {
+	client  APIClient
+	opts    StashRiverOptions
+	batches chan StashBatch
+	started bool
+	lock    sync.Mutex
+}
+
+// NewStashRiver creates a StashRiver which requests stashes using the given
+// client. Call Run to start it.
+func NewStashRiver(client APIClient, opts StashRiverOptions) (*StashRiver, error) {
+	if client == nil {
+		return nil, ErrInvalidStashRiver
+	}
+	if opts.PollInterval < 0 || opts.MaxBackoff < 0 {
+		return nil, ErrInvalidStashRiver
+	}
+	if opts.PollInterval == 0 {
+		opts.PollInterval = DefaultRiverPollInterval
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = DefaultRiverMaxBackoff
+	}
+	return &StashRiver{
+		client:  client,
+		opts:    opts,
+		batches: make(chan StashBatch),
+	}, nil
+}
+
+// Batches returns the channel on which batches are delivered. The channel is
+// closed when Run returns.
+func (r *StashRiver) Batches() <-chan StashBatch {
+	return r.batches
+}
+
+// Run requests batches until the context is canceled or the API rejects a
+// request outright, and returns that error. Other failures, including
+// connection errors, are retried with exponential backoff. Run may only be
+// called once.
+func (r *StashRiver) Run(ctx context.Context) error {
+	r.lock.Lock()
+	if r.started {
+		r.lock.Unlock()
+		return ErrStashRiverStarted
+	}
+	r.started = true
+	r.lock.Unlock()
+
+	defer close(r.batches)
+	return r.run(ctx)
+}
+
+func (r *StashRiver) run(ctx context.Context) error {
+	id, err := r.startID(ctx)
+	if err != nil {
+		return err
+	}
+
+	failures := 0
+	for {
+		resp, err := r.client.GetStashesContext(ctx, GetStashOptions{ID: id})
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if isFatalRiverError(err) {
+				return err
+			}
+			failures++
+			if err := sleepContext(ctx, r.backoff(failures)); err != nil {
+				return err
+			}
+			continue
+		}
+		failures = 0
+
+		// When the next change ID has not advanced, we have caught up with
+		// the latest stashes and must wait for new ones to be published.
+		if resp.NextChangeID == "" || resp.NextChangeID == id {
+			if err := sleepContext(ctx, r.opts.PollInterval); err != nil {
+				return err
+			}
+			continue
+		}
+
+		batch := StashBatch{
+			ChangeID:     id,
+			NextChangeID: resp.NextChangeID,
+			Stashes:      resp.Stashes,
+		}
+		select {
+		case r.batches <- batch:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		id = batch.NextChangeID
+	}
+}
+
+// Commit marks a batch as processed by saving its NextChangeID with the
+// Checkpointer, so that a restarted river does not deliver it again. Batches
+// should be committed in the order they were received. Commit does nothing
+// when the river has no Checkpointer.
+func (r *StashRiver) Commit(batch StashBatch) error {
+	if r.opts.Checkpointer == nil {
+		return nil
+	}
+	return r.opts.Checkpointer.Save(batch.NextChangeID)
+}
+
+// isFatalRiverError reports whether err is a definitive rejection by the API,
+// which retrying cannot fix. Transport errors, such as refused connections
+// and truncated responses, are transient for a long-running river.
+func isFatalRiverError(err error) bool {
+	for _, fatal := range []error{
+		ErrBadRequest,
+		ErrForbidden,
+		ErrNotFound,
+		ErrUnknownFailure,
+		ErrInvalidToken,
+		ErrMissingScope,
+	} {
+		if errors.Is(err, fatal) {
+			return true
+		}
+	}
+	return false
+}
+
+// startID returns the change ID to start from: the saved checkpoint, then the
+// configured StartID, then the latest change ID.
+func (r *StashRiver) startID(ctx context.Context) (string, error) {
+	if r.opts.Checkpointer != nil {
+		id, err := r.opts.Checkpointer.Load()
+		if err != nil {
+			return "", err
+		}
+		if id != "" {
+			return id, nil
+		}
+	}
+	if r.opts.StartID != "" {
+		return r.opts.StartID, nil
+	}
+	return r.client.GetLatestStashIDContext(ctx)
+}
+
+func (r *StashRiver) backoff(failures int) time.Duration {
+	d := r.opts.PollInterval
+	for i := 1; i < failures && d < r.opts.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > r.opts.MaxBackoff {
+		d = r.opts.MaxBackoff
+	}
+	return d
+}
+
+// FileCheckpointer is a Checkpointer which stores the change ID in a file.
+// Writes are atomic, so a crash never leaves a partially written change ID.
+type FileCheckpointer struct {
+	path string
+	lock sync.Mutex
+}
+
+// NewFileCheckpointer returns a FileCheckpointer which stores the change ID
+// at the given path. The file is created on the first call to Save.
+func NewFileCheckpointer(path string) *FileCheckpointer {
+	return &FileCheckpointer{path: path}
+}
+
+// Load returns the saved change ID, or an empty string if the file does not
+// exist.
+func (f *FileCheckpointer) Load() (string, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	b, err := ioutil.ReadFile(f.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Save writes the change ID to a temporary file and renames it into place.
+func (f *FileCheckpointer) Save(changeID string) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(f.path), filepath.Base(f.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(changeID + "\n"); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
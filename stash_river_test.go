@@ -0,0 +1,238 @@
+package poeapi
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeStashClient serves stash responses from memory. Methods other than
+// those used by StashRiver are not implemented.
+type fakeStashClient struct {
+	APIClient
+
+	pages  map[string]StashResponse
+	errors []error
+	lock   sync.Mutex
+}
+
+func (f *fakeStashClient) GetStashesContext(ctx context.Context, opts GetStashOptions) (StashResponse, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if len(f.errors) > 0 {
+		err := f.errors[0]
+		f.errors = f.errors[1:]
+		return StashResponse{}, err
+	}
+	return f.pages[opts.ID], nil
+}
+
+func (f *fakeStashClient) GetLatestStashIDContext(ctx context.Context) (string, error) {
+	return "a", nil
+}
+
+type memoryCheckpointer struct {
+	id   string
+	lock sync.Mutex
+}
+
+func (m *memoryCheckpointer) Load() (string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.id, nil
+}
+
+func (m *memoryCheckpointer) Save(id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.id = id
+	return nil
+}
+
+func newFakeStashClient() *fakeStashClient {
+	return &fakeStashClient{
+		pages: map[string]StashResponse{
+			"a": {NextChangeID: "b", Stashes: []Stash{{ID: "1"}}},
+			"b": {NextChangeID: "c", Stashes: []Stash{{ID: "2"}}},
+			"c": {NextChangeID: "d", Stashes: []Stash{{ID: "3"}}},
+			"d": {NextChangeID: "d"},
+		},
+	}
+}
+
+func runRiver(t *testing.T, client APIClient, opts StashRiverOptions) (*StashRiver, context.CancelFunc, chan error) {
+	river, err := NewStashRiver(client, opts)
+	if err != nil {
+		t.Fatalf("failed to create stash river: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	done := make(chan error, 1)
+	go func() { done <- river.Run(ctx) }()
+	return river, cancel, done
+}
+
+func TestStashRiver(t *testing.T) {
+	var (
+		checkpoint          = &memoryCheckpointer{}
+		river, cancel, done = runRiver(t, newFakeStashClient(), StashRiverOptions{
+			Checkpointer: checkpoint,
+			PollInterval: time.Millisecond,
+		})
+	)
+	defer cancel()
+
+	for _, expected := range []string{"a", "b", "c"} {
+		batch := <-river.Batches()
+		if batch.ChangeID != expected {
+			t.Fatalf("unexpected batch: expected %s, got %s", expected,
+				batch.ChangeID)
+		}
+		if expected == "c" {
+			// Leave the last batch uncommitted.
+			break
+		}
+		if err := river.Commit(batch); err != nil {
+			t.Fatalf("failed to commit batch: %v", err)
+		}
+		if id, _ := checkpoint.Load(); id != batch.NextChangeID {
+			t.Fatalf("unexpected checkpoint after commit: expected %s, got %s",
+				batch.NextChangeID, id)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("unexpected river error: %v", err)
+	}
+	if _, ok := <-river.Batches(); ok {
+		t.Fatal("failed to close batch channel")
+	}
+
+	// The third batch was received but not committed, so the checkpoint
+	// should point to it.
+	if id, _ := checkpoint.Load(); id != "c" {
+		t.Fatalf("unexpected checkpoint: expected c, got %s", id)
+	}
+}
+
+func TestStashRiverResume(t *testing.T) {
+	river, cancel, _ := runRiver(t, newFakeStashClient(), StashRiverOptions{
+		StartID:      "a",
+		Checkpointer: &memoryCheckpointer{id: "b"},
+		PollInterval: time.Millisecond,
+	})
+	defer cancel()
+
+	if batch := <-river.Batches(); batch.ChangeID != "b" {
+		t.Fatalf("failed to resume from checkpoint: got %s", batch.ChangeID)
+	}
+}
+
+func TestStashRiverCommitWithoutCheckpointer(t *testing.T) {
+	river, cancel, _ := runRiver(t, newFakeStashClient(), StashRiverOptions{
+		PollInterval: time.Millisecond,
+	})
+	defer cancel()
+
+	if err := river.Commit(<-river.Batches()); err != nil {
+		t.Fatalf("failed to commit batch without checkpointer: %v", err)
+	}
+}
+
+func TestStashRiverRetry(t *testing.T) {
+	client := newFakeStashClient()
+	client.errors = []error{ErrServerFailure, ErrRateLimited}
+	river, cancel, _ := runRiver(t, client, StashRiverOptions{
+		PollInterval: time.Millisecond,
+	})
+	defer cancel()
+
+	if batch := <-river.Batches(); batch.ChangeID != "a" {
+		t.Fatalf("unexpected batch after retries: %s", batch.ChangeID)
+	}
+}
+
+func TestStashRiverConnectionError(t *testing.T) {
+	var (
+		failed    int32
+		transport = RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if atomic.AddInt32(&failed, 1) == 1 {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+			}
+			return http.DefaultTransport.RoundTrip(req)
+		})
+		c = &client{
+			host:       testHost,
+			limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+			httpClient: &http.Client{Timeout: testTimeout, Transport: transport},
+		}
+		river, cancel, _ = runRiver(t, c, StashRiverOptions{
+			StartID:      "a",
+			PollInterval: time.Millisecond,
+		})
+	)
+	defer cancel()
+
+	if batch := <-river.Batches(); batch.ChangeID != "a" || len(batch.Stashes) == 0 {
+		t.Fatalf("unexpected batch after connection error: %+v", batch)
+	}
+}
+
+func TestStashRiverFatalError(t *testing.T) {
+	client := newFakeStashClient()
+	client.errors = []error{ErrBadRequest}
+	_, cancel, done := runRiver(t, client, StashRiverOptions{
+		PollInterval: time.Millisecond,
+	})
+	defer cancel()
+
+	if err := <-done; err != ErrBadRequest {
+		t.Fatalf("failed to stop on non-retryable error: %v", err)
+	}
+}
+
+func TestStashRiverRunTwice(t *testing.T) {
+	river, cancel, _ := runRiver(t, newFakeStashClient(), StashRiverOptions{})
+	defer cancel()
+	<-river.Batches()
+	if err := river.Run(context.Background()); err != ErrStashRiverStarted {
+		t.Fatal("failed to detect second run")
+	}
+}
+
+func TestNewStashRiverWithInvalidOptions(t *testing.T) {
+	if _, err := NewStashRiver(nil, StashRiverOptions{}); err != ErrInvalidStashRiver {
+		t.Fatal("failed to detect missing client")
+	}
+	opts := StashRiverOptions{PollInterval: -1}
+	if _, err := NewStashRiver(newFakeStashClient(), opts); err != ErrInvalidStashRiver {
+		t.Fatal("failed to detect negative poll interval")
+	}
+}
+
+func TestFileCheckpointer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poeapi")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f := NewFileCheckpointer(filepath.Join(dir, "checkpoint"))
+	if id, err := f.Load(); err != nil || id != "" {
+		t.Fatalf("unexpected initial checkpoint: %q, %v", id, err)
+	}
+	if err := f.Save("123-456"); err != nil {
+		t.Fatalf("failed to save checkpoint: %v", err)
+	}
+	if id, err := NewFileCheckpointer(f.path).Load(); err != nil || id != "123-456" {
+		t.Fatalf("unexpected saved checkpoint: %q, %v", id, err)
+	}
+}
@@ -1,6 +1,7 @@
 package poeapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
@@ -22,9 +23,13 @@ func (opts GetStashOptions) toQueryParams() string {
 }
 
 func (c *client) GetStashes(opts GetStashOptions) (StashResponse, error) {
+	return c.GetStashesContext(context.Background(), opts)
+}
+
+func (c *client) GetStashesContext(ctx context.Context, opts GetStashOptions) (StashResponse, error) {
 	url := fmt.Sprintf("%s?%s", c.formatURL(stashTabsEndpoint),
 		opts.toQueryParams())
-	resp, err := c.get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return StashResponse{}, err
 	}
@@ -44,6 +49,10 @@ type latestChange struct {
 }
 
 func (c *client) GetLatestStashID() (string, error) {
+	return c.GetLatestStashIDContext(context.Background())
+}
+
+func (c *client) GetLatestStashIDContext(ctx context.Context) (string, error) {
 	var url string
 	if c.useSSL {
 		url = fmt.Sprintf("https://%s/api/Data/GetStats", c.ninjaHost)
@@ -51,7 +60,7 @@ func (c *client) GetLatestStashID() (string, error) {
 		url = fmt.Sprintf("http://%s/api/Data/GetStats", c.ninjaHost)
 	}
 
-	resp, err := c.get(url)
+	resp, err := c.get(ctx, url)
 	if err != nil {
 		return "", err
 	}
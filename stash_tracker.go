@@ -0,0 +1,296 @@
+package poeapi
+
+import (
+	"container/list"
+	"encoding/json"
+	"io"
+	"sort"
+	"sync"
+)
+
+// StashEventType identifies the kind of change described by a StashEvent.
+type StashEventType int
+
+const (
+	// ItemListed is emitted for items which were not in the previous state of
+	// their stash, including every item of a stash seen for the first time.
+	ItemListed StashEventType = iota + 1
+
+	// ItemRemoved is emitted for items which are no longer in their stash,
+	// whether they were sold, moved elsewhere, or delisted.
+	ItemRemoved
+
+	// PriceChanged is emitted when the effective price of an item changes,
+	// either through its own note or through the name of its stash.
+	PriceChanged
+
+	// ItemMoved is emitted when an item moves to a new position within its
+	// stash. Items moved to another stash are reported as removed from one
+	// stash and listed in the other.
+	ItemMoved
+
+	// StashMadePrivate is emitted when a tracked stash is made private. Its
+	// items are not reported individually.
+	StashMadePrivate
+)
+
+var stashEventTypeNames = map[StashEventType]string{
+	ItemListed:       "ItemListed",
+	ItemRemoved:      "ItemRemoved",
+	PriceChanged:     "PriceChanged",
+	ItemMoved:        "ItemMoved",
+	StashMadePrivate: "StashMadePrivate",
+}
+
+// String returns the name of the event type, such as "ItemListed".
+func (t StashEventType) String() string {
+	return stashEventTypeNames[t]
+}
+
+// StashEvent describes a single change to a public stash.
+type StashEvent struct {
+	Type        StashEventType
+	StashID     string
+	AccountName string
+	League      string
+
+	// The ID of the affected item. Empty for StashMadePrivate.
+	ItemID string
+
+	// The current state of the item. Since the tracker does not retain whole
+	// items, only ID and position are set for ItemRemoved.
+	Item Item
+
+	// Prices before and after the change. The zero Price means the item was
+	// not priced.
+	OldPrice Price
+	NewPrice Price
+
+	// Positions before and after the change, for ItemMoved.
+	OldX, OldY int64
+	NewX, NewY int64
+}
+
+// StashTrackerOptions contains settings for a StashTracker.
+type StashTrackerOptions struct {
+	// The maximum number of stashes to remember. When exceeded, the least
+	// recently updated stash is forgotten, and all of its items will be
+	// reported as listed if it is seen again. Zero means no limit.
+	MaxStashes int
+}
+
+// StashTracker compares each update of a public stash with its previous state,
+// so that the full contents of a stash from GetStashes can be turned into a
+// stream of changes. Only the ID, position and price of each item are
+// retained. It is safe for concurrent use.
+type StashTracker struct {
+	opts    StashTrackerOptions
+	stashes map[string]*list.Element
+	recent  *list.List
+	lock    sync.Mutex
+}
+
+// trackedStash is the retained state of a single stash.
+type trackedStash struct {
+	ID          string                 `json:"id"`
+	AccountName string                 `json:"accountName"`
+	League      string                 `json:"league"`
+	Items       map[string]trackedItem `json:"items"`
+}
+
+// trackedItem is the retained state of a single item.
+type trackedItem struct {
+	X     int64 `json:"x"`
+	Y     int64 `json:"y"`
+	Price Price `json:"price"`
+}
+
+// trackerState is the format in which the tracker's state is saved. Stashes
+// are ordered from least to most recently updated.
+type trackerState struct {
+	Stashes []trackedStash `json:"stashes"`
+}
+
+// NewStashTracker creates an empty StashTracker.
+func NewStashTracker(opts StashTrackerOptions) (*StashTracker, error) {
+	if opts.MaxStashes < 0 {
+		return nil, ErrInvalidStashTracker
+	}
+	return &StashTracker{
+		opts:    opts,
+		stashes: make(map[string]*list.Element),
+		recent:  list.New(),
+	}, nil
+}
+
+// Len returns the number of stashes being tracked.
+func (t *StashTracker) Len() int {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.recent.Len()
+}
+
+// Update records the new state of each stash and returns the changes since
+// the previous state, in the order of the stashes and their items. Removed
+// items are reported after the other events for their stash.
+func (t *StashTracker) Update(stashes ...Stash) []StashEvent {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var events []StashEvent
+	for _, s := range stashes {
+		events = append(events, t.update(s)...)
+	}
+	return events
+}
+
+func (t *StashTracker) update(s Stash) []StashEvent {
+	var previous *trackedStash
+	if e, ok := t.stashes[s.ID]; ok {
+		previous = e.Value.(*trackedStash)
+	}
+
+	if !s.Public {
+		if previous == nil {
+			return nil
+		}
+		t.remove(s.ID)
+		return []StashEvent{{
+			Type:        StashMadePrivate,
+			StashID:     s.ID,
+			AccountName: previous.AccountName,
+			League:      previous.League,
+		}}
+	}
+
+	current := &trackedStash{
+		ID:          s.ID,
+		AccountName: s.AccountName,
+		League:      s.League,
+		Items:       make(map[string]trackedItem, len(s.Items)),
+	}
+	var events []StashEvent
+	event := func(typ StashEventType, item Item) StashEvent {
+		return StashEvent{
+			Type:        typ,
+			StashID:     s.ID,
+			AccountName: s.AccountName,
+			League:      s.League,
+			ItemID:      item.ID,
+			Item:        item,
+		}
+	}
+
+	for _, item := range s.Items {
+		price, _ := ItemPrice(item, s)
+		// Prices which are not finite never compare equal to themselves and
+		// cannot be saved, so treat them as missing.
+		if !isFinite(price.Amount) {
+			price = Price{}
+		}
+		state := trackedItem{X: item.XPosition, Y: item.YPosition, Price: price}
+		current.Items[item.ID] = state
+
+		var old trackedItem
+		found := false
+		if previous != nil {
+			old, found = previous.Items[item.ID]
+		}
+		if !found {
+			e := event(ItemListed, item)
+			e.NewPrice = price
+			events = append(events, e)
+			continue
+		}
+		if old.Price != price {
+			e := event(PriceChanged, item)
+			e.OldPrice, e.NewPrice = old.Price, price
+			events = append(events, e)
+		}
+		if old.X != state.X || old.Y != state.Y {
+			e := event(ItemMoved, item)
+			e.OldX, e.OldY = old.X, old.Y
+			e.NewX, e.NewY = state.X, state.Y
+			events = append(events, e)
+		}
+	}
+
+	if previous != nil {
+		var removed []string
+		for id := range previous.Items {
+			if _, ok := current.Items[id]; !ok {
+				removed = append(removed, id)
+			}
+		}
+		sort.Strings(removed)
+		for _, id := range removed {
+			old := previous.Items[id]
+			e := event(ItemRemoved, Item{ID: id, XPosition: old.X, YPosition: old.Y})
+			e.OldPrice = old.Price
+			e.OldX, e.OldY = old.X, old.Y
+			events = append(events, e)
+		}
+	}
+
+	t.set(current)
+	return events
+}
+
+// set stores the state of a stash as the most recently updated, evicting the
+// least recently updated stash if the limit is exceeded.
+func (t *StashTracker) set(s *trackedStash) {
+	if e, ok := t.stashes[s.ID]; ok {
+		e.Value = s
+		t.recent.MoveToFront(e)
+		return
+	}
+	t.stashes[s.ID] = t.recent.PushFront(s)
+
+	if t.opts.MaxStashes > 0 && t.recent.Len() > t.opts.MaxStashes {
+		t.remove(t.recent.Back().Value.(*trackedStash).ID)
+	}
+}
+
+func (t *StashTracker) remove(id string) {
+	if e, ok := t.stashes[id]; ok {
+		t.recent.Remove(e)
+		delete(t.stashes, id)
+	}
+}
+
+// Save writes the tracker's state as JSON, so that it can be restored with
+// Load after a restart.
+func (t *StashTracker) Save(w io.Writer) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	state := trackerState{Stashes: make([]trackedStash, 0, t.recent.Len())}
+	for e := t.recent.Back(); e != nil; e = e.Prev() {
+		state.Stashes = append(state.Stashes, *e.Value.(*trackedStash))
+	}
+	return json.NewEncoder(w).Encode(state)
+}
+
+// Load replaces the tracker's state with one written by Save. If the saved
+// state holds more stashes than MaxStashes, the least recently updated are
+// discarded.
+func (t *StashTracker) Load(r io.Reader) error {
+	var state trackerState
+	if err := json.NewDecoder(r).Decode(&state); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.stashes = make(map[string]*list.Element, len(state.Stashes))
+	t.recent = list.New()
+	for n := range state.Stashes {
+		s := state.Stashes[n]
+		if s.Items == nil {
+			s.Items = make(map[string]trackedItem)
+		}
+		t.set(&s)
+	}
+	return nil
+}
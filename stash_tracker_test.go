@@ -0,0 +1,192 @@
+package poeapi
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func trackerStash(id string, items ...Item) Stash {
+	return Stash{
+		ID:          id,
+		AccountName: "Account1",
+		League:      "Standard",
+		Index:       "~price 1 chaos",
+		Public:      true,
+		Items:       items,
+	}
+}
+
+func eventTypes(events []StashEvent) []StashEventType {
+	types := make([]StashEventType, 0, len(events))
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+	return types
+}
+
+func TestNewStashTrackerWithInvalidOptions(t *testing.T) {
+	_, err := NewStashTracker(StashTrackerOptions{MaxStashes: -1})
+	if !errors.Is(err, ErrInvalidStashTracker) {
+		t.Fatalf("failed to detect invalid tracker options: %v", err)
+	}
+}
+
+func TestStashTrackerEvents(t *testing.T) {
+	tracker, err := NewStashTracker(StashTrackerOptions{})
+	if err != nil {
+		t.Fatalf("failed to create tracker: %v", err)
+	}
+
+	events := tracker.Update(trackerStash("stash1",
+		Item{ID: "a", Note: "~b/o 5 chaos"},
+		Item{ID: "b", XPosition: 1},
+	))
+	if len(events) != 2 || events[0].Type != ItemListed || events[1].Type != ItemListed {
+		t.Fatalf("failed to list new items: %v", eventTypes(events))
+	}
+	if events[1].NewPrice.Amount != 1 || events[1].AccountName != "Account1" {
+		t.Fatalf("failed to inherit price from stash name: %+v", events[1])
+	}
+
+	events = tracker.Update(trackerStash("stash1",
+		Item{ID: "a", Note: "~b/o 4 chaos", XPosition: 2, YPosition: 3},
+		Item{ID: "c"},
+	))
+	if len(events) != 4 {
+		t.Fatalf("unexpected events: %v", eventTypes(events))
+	}
+	if e := events[0]; e.Type != PriceChanged || e.ItemID != "a" ||
+		e.OldPrice.Amount != 5 || e.NewPrice.Amount != 4 {
+		t.Fatalf("failed to detect price change: %+v", e)
+	}
+	if e := events[1]; e.Type != ItemMoved || e.OldX != 0 || e.NewX != 2 || e.NewY != 3 {
+		t.Fatalf("failed to detect item move: %+v", e)
+	}
+	if e := events[2]; e.Type != ItemListed || e.ItemID != "c" {
+		t.Fatalf("failed to detect listing: %+v", e)
+	}
+	if e := events[3]; e.Type != ItemRemoved || e.ItemID != "b" || e.OldPrice.Amount != 1 {
+		t.Fatalf("failed to detect removal: %+v", e)
+	}
+
+	if events = tracker.Update(trackerStash("stash1",
+		Item{ID: "a", Note: "~b/o 4 chaos", XPosition: 2, YPosition: 3},
+		Item{ID: "c"},
+	)); len(events) != 0 {
+		t.Fatalf("unexpected events for unchanged stash: %v", eventTypes(events))
+	}
+
+	events = tracker.Update(Stash{ID: "stash1"})
+	if len(events) != 1 || events[0].Type != StashMadePrivate || events[0].League != "Standard" {
+		t.Fatalf("failed to detect private stash: %+v", events)
+	}
+	if tracker.Len() != 0 {
+		t.Fatal("failed to forget private stash")
+	}
+	if events = tracker.Update(Stash{ID: "stash2"}); len(events) != 0 {
+		t.Fatalf("unexpected events for unknown private stash: %v", eventTypes(events))
+	}
+}
+
+func TestStashTrackerUnchangedPrices(t *testing.T) {
+	tracker, _ := NewStashTracker(StashTrackerOptions{})
+	stash := trackerStash("stash1",
+		Item{ID: "a", Note: "~b/o 5 chaos"},
+		Item{ID: "b", Note: "~b/o nan chaos"},
+		Item{ID: "c", Note: "~b/o inf chaos"},
+	)
+	tracker.Update(stash)
+	if events := tracker.Update(stash); len(events) != 0 {
+		t.Fatalf("unexpected events for unchanged prices: %v", eventTypes(events))
+	}
+	if err := tracker.Save(&bytes.Buffer{}); err != nil {
+		t.Fatalf("failed to save tracker state: %v", err)
+	}
+}
+
+func TestStashTrackerEmptiedStash(t *testing.T) {
+	tracker, _ := NewStashTracker(StashTrackerOptions{})
+	tracker.Update(trackerStash("stash1", Item{ID: "a"}, Item{ID: "b"}))
+	events := tracker.Update(trackerStash("stash1"))
+	if len(events) != 2 || events[0].Type != ItemRemoved || events[1].Type != ItemRemoved {
+		t.Fatalf("failed to remove items from emptied stash: %v", eventTypes(events))
+	}
+}
+
+func TestStashTrackerMaxStashes(t *testing.T) {
+	tracker, _ := NewStashTracker(StashTrackerOptions{MaxStashes: 2})
+	tracker.Update(trackerStash("stash1", Item{ID: "a"}))
+	tracker.Update(trackerStash("stash2", Item{ID: "b"}))
+	tracker.Update(trackerStash("stash1", Item{ID: "a"}))
+	tracker.Update(trackerStash("stash3", Item{ID: "c"}))
+
+	if tracker.Len() != 2 {
+		t.Fatalf("failed to bound tracked stashes. expected 2, got %d", tracker.Len())
+	}
+	// stash2 was least recently updated, so it was forgotten.
+	if events := tracker.Update(trackerStash("stash2", Item{ID: "b"})); len(events) != 1 ||
+		events[0].Type != ItemListed {
+		t.Fatalf("failed to evict least recently updated stash: %v", eventTypes(events))
+	}
+	if events := tracker.Update(trackerStash("stash3", Item{ID: "c"})); len(events) != 0 {
+		t.Fatalf("unexpectedly evicted recent stash: %v", eventTypes(events))
+	}
+}
+
+func TestStashTrackerPersistence(t *testing.T) {
+	tracker, _ := NewStashTracker(StashTrackerOptions{})
+	tracker.Update(trackerStash("stash1", Item{ID: "a", Note: "~b/o 5 chaos"}))
+	tracker.Update(trackerStash("stash2", Item{ID: "b"}))
+
+	var buf bytes.Buffer
+	if err := tracker.Save(&buf); err != nil {
+		t.Fatalf("failed to save tracker state: %v", err)
+	}
+
+	restored, _ := NewStashTracker(StashTrackerOptions{MaxStashes: 1})
+	if err := restored.Load(&buf); err != nil {
+		t.Fatalf("failed to load tracker state: %v", err)
+	}
+	if restored.Len() != 1 {
+		t.Fatalf("failed to apply stash limit to loaded state. got %d stashes", restored.Len())
+	}
+	if events := restored.Update(trackerStash("stash2", Item{ID: "b"})); len(events) != 0 {
+		t.Fatalf("failed to restore most recent stash: %v", eventTypes(events))
+	}
+
+	if err := restored.Load(bytes.NewBufferString("{")); err == nil {
+		t.Fatal("failed to detect invalid state")
+	}
+}
+
+func TestStashTrackerFixture(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+
+	tracker, _ := NewStashTracker(StashTrackerOptions{})
+	count := 0
+	for _, s := range stashes.Stashes {
+		if s.Public {
+			count += len(s.Items)
+		}
+	}
+	if events := tracker.Update(stashes.Stashes...); len(events) != count {
+		t.Fatalf("failed to list fixture items. expected %d, got %d", count, len(events))
+	}
+	if events := tracker.Update(stashes.Stashes...); len(events) != 0 {
+		t.Fatalf("unexpected events for repeated update: %v", eventTypes(events))
+	}
+}
+
+func TestStashEventTypeString(t *testing.T) {
+	if PriceChanged.String() != "PriceChanged" {
+		t.Fatalf("unexpected event type name: %s", PriceChanged)
+	}
+}
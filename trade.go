@@ -0,0 +1,446 @@
+package poeapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TradeFetchBatchSize is the maximum number of listings the trade API returns
+// per fetch request. Fetch splits larger requests into batches of this size.
+const TradeFetchBatchSize = 10
+
+// TradeStatus filters trade listings by the online status of the seller.
+type TradeStatus string
+
+const (
+	// TradeOnline matches sellers who are online.
+	TradeOnline TradeStatus = "online"
+
+	// TradeOnlineLeague matches sellers who are online in the searched
+	// league.
+	TradeOnlineLeague TradeStatus = "onlineleague"
+
+	// TradeAny matches all sellers.
+	TradeAny TradeStatus = "any"
+)
+
+// Types of trade stat groups.
+const (
+	TradeStatsAnd   = "and"
+	TradeStatsNot   = "not"
+	TradeStatsIf    = "if"
+	TradeStatsCount = "count"
+)
+
+// TradeRange is an optional minimum and maximum for a trade filter.
+type TradeRange struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// TradeMin returns a range with only a minimum.
+func TradeMin(min float64) TradeRange {
+	return TradeRange{Min: &min}
+}
+
+// TradeMax returns a range with only a maximum.
+func TradeMax(max float64) TradeRange {
+	return TradeRange{Max: &max}
+}
+
+// TradeBetween returns a range with a minimum and maximum.
+func TradeBetween(min, max float64) TradeRange {
+	return TradeRange{Min: &min, Max: &max}
+}
+
+// TradeStatFilter matches items with a stat, identified by a stat ID such as
+// "explicit.stat_3299347043". The stat IDs are listed by the trade API at
+// /api/trade/data/stats, and the mods package can load them.
+type TradeStatFilter struct {
+	ID       string     `json:"id"`
+	Value    TradeRange `json:"value"`
+	Disabled bool       `json:"disabled"`
+}
+
+// TradeStatGroup combines stat filters. The Type is one of the TradeStats
+// constants, and Value bounds the number of matching filters for
+// TradeStatsCount groups.
+type TradeStatGroup struct {
+	Type    string            `json:"type"`
+	Filters []TradeStatFilter `json:"filters"`
+	Value   *TradeRange       `json:"value,omitempty"`
+}
+
+// TradeSocketFilter matches the number and colours of an item's sockets or
+// links. Zero fields are ignored.
+type TradeSocketFilter struct {
+	Min int `json:"min,omitempty"`
+	Max int `json:"max,omitempty"`
+	R   int `json:"r,omitempty"`
+	G   int `json:"g,omitempty"`
+	B   int `json:"b,omitempty"`
+	W   int `json:"w,omitempty"`
+}
+
+// TradePriceFilter matches listings by price. When Currency is set, only
+// listings in that currency are matched; otherwise prices are compared in
+// chaos orb equivalents.
+type TradePriceFilter struct {
+	TradeRange
+	Currency Currency `json:"option,omitempty"`
+}
+
+// TradeQuery describes a trade search. Queries may be built as struct
+// literals or with the With methods, such as:
+//
+//	q := poeapi.NewTradeQuery().
+//	    WithType("Vaal Regalia").
+//	    WithStat("explicit.stat_3299347043", poeapi.TradeMin(90)).
+//	    WithLinks(poeapi.TradeSocketFilter{Min: 6}).
+//	    WithPrice(poeapi.TradeMax(50), poeapi.CurrencyChaos)
+type TradeQuery struct {
+	Status TradeStatus
+
+	// The item's name and base type, such as "Kaom's Heart" and "Glorious
+	// Plate".
+	Name string
+	Type string
+
+	Stats   []TradeStatGroup
+	Sockets *TradeSocketFilter
+	Links   *TradeSocketFilter
+	Price   *TradePriceFilter
+}
+
+// NewTradeQuery returns an empty query for listings from online sellers.
+func NewTradeQuery() *TradeQuery {
+	return &TradeQuery{Status: TradeOnline}
+}
+
+// WithStatus sets the online status of sellers to match.
+func (q *TradeQuery) WithStatus(status TradeStatus) *TradeQuery {
+	q.Status = status
+	return q
+}
+
+// WithName sets the item name to match.
+func (q *TradeQuery) WithName(name string) *TradeQuery {
+	q.Name = name
+	return q
+}
+
+// WithType sets the item base type to match.
+func (q *TradeQuery) WithType(typ string) *TradeQuery {
+	q.Type = typ
+	return q
+}
+
+// WithStat adds a stat filter to the query's first "and" group, creating the
+// group if needed.
+func (q *TradeQuery) WithStat(id string, value TradeRange) *TradeQuery {
+	filter := TradeStatFilter{ID: id, Value: value}
+	for n := range q.Stats {
+		if q.Stats[n].Type == TradeStatsAnd {
+			q.Stats[n].Filters = append(q.Stats[n].Filters, filter)
+			return q
+		}
+	}
+	return q.WithStatGroup(TradeStatGroup{
+		Type:    TradeStatsAnd,
+		Filters: []TradeStatFilter{filter},
+	})
+}
+
+// WithStatGroup adds a group of stat filters.
+func (q *TradeQuery) WithStatGroup(group TradeStatGroup) *TradeQuery {
+	q.Stats = append(q.Stats, group)
+	return q
+}
+
+// WithSockets sets the socket filter.
+func (q *TradeQuery) WithSockets(f TradeSocketFilter) *TradeQuery {
+	q.Sockets = &f
+	return q
+}
+
+// WithLinks sets the link filter.
+func (q *TradeQuery) WithLinks(f TradeSocketFilter) *TradeQuery {
+	q.Links = &f
+	return q
+}
+
+// WithPrice sets the price filter. Pass an empty currency to compare prices
+// in chaos orb equivalents.
+func (q *TradeQuery) WithPrice(r TradeRange, currency Currency) *TradeQuery {
+	q.Price = &TradePriceFilter{TradeRange: r, Currency: currency}
+	return q
+}
+
+type tradeStatus struct {
+	Option TradeStatus `json:"option"`
+}
+
+type tradeFilterGroup struct {
+	Filters map[string]interface{} `json:"filters"`
+}
+
+type tradeQueryBody struct {
+	Status  *tradeStatus                `json:"status,omitempty"`
+	Name    string                      `json:"name,omitempty"`
+	Type    string                      `json:"type,omitempty"`
+	Stats   []TradeStatGroup            `json:"stats"`
+	Filters map[string]tradeFilterGroup `json:"filters,omitempty"`
+}
+
+type tradeRequest struct {
+	Query tradeQueryBody    `json:"query"`
+	Sort  map[string]string `json:"sort"`
+}
+
+// MarshalJSON encodes the query as the body of a trade search request, sorted
+// by ascending price.
+func (q TradeQuery) MarshalJSON() ([]byte, error) {
+	body := tradeQueryBody{
+		Name:    q.Name,
+		Type:    q.Type,
+		Stats:   q.Stats,
+		Filters: make(map[string]tradeFilterGroup),
+	}
+	if q.Status != "" {
+		body.Status = &tradeStatus{Option: q.Status}
+	}
+	if body.Stats == nil {
+		body.Stats = []TradeStatGroup{}
+	}
+
+	sockets := make(map[string]interface{})
+	if q.Sockets != nil {
+		sockets["sockets"] = q.Sockets
+	}
+	if q.Links != nil {
+		sockets["links"] = q.Links
+	}
+	if len(sockets) > 0 {
+		body.Filters["socket_filters"] = tradeFilterGroup{Filters: sockets}
+	}
+	if q.Price != nil {
+		body.Filters["trade_filters"] = tradeFilterGroup{
+			Filters: map[string]interface{}{"price": q.Price},
+		}
+	}
+	if len(body.Filters) == 0 {
+		body.Filters = nil
+	}
+
+	return json.Marshal(tradeRequest{
+		Query: body,
+		Sort:  map[string]string{"price": "asc"},
+	})
+}
+
+// TradeSearchOptions contains the request parameters for trade searches.
+type TradeSearchOptions struct {
+	// The league to search, such as "Standard".
+	League string
+
+	// The realm to search. Defaults to "pc".
+	Realm string
+
+	Query *TradeQuery
+}
+
+func validateTradeSearchOptions(opts TradeSearchOptions) error {
+	if opts.League == "" || opts.Query == nil {
+		return ErrInvalidTradeSearch
+	}
+	if opts.Realm != "" {
+		if _, ok := validRealms[opts.Realm]; !ok {
+			return ErrInvalidRealm
+		}
+	}
+	return nil
+}
+
+// tradeSearchPath returns the search endpoint for a league. As with the
+// character endpoints, the realm is omitted for PC.
+func tradeSearchPath(realm, league string) string {
+	path := tradeSearchEndpoint
+	if realm != "" && realm != defaultRealm {
+		path += "/" + realm
+	}
+	return path + "/" + url.PathEscape(league)
+}
+
+// TradeSearchResult is the response to a trade search.
+type TradeSearchResult struct {
+	// The ID of the search, which is required to fetch its listings.
+	ID string `json:"id"`
+
+	// The IDs of the matching listings, cheapest first. At most 100 IDs are
+	// returned.
+	Result []string `json:"result"`
+
+	// The total number of matching listings.
+	Total int `json:"total"`
+
+	Complexity int  `json:"complexity"`
+	Inexact    bool `json:"inexact"`
+}
+
+func (c *client) Search(opts TradeSearchOptions) (TradeSearchResult, error) {
+	return c.SearchContext(context.Background(), opts)
+}
+
+func (c *client) SearchContext(ctx context.Context, opts TradeSearchOptions) (TradeSearchResult, error) {
+	if err := validateTradeSearchOptions(opts); err != nil {
+		return TradeSearchResult{}, err
+	}
+	body, err := json.Marshal(opts.Query)
+	if err != nil {
+		return TradeSearchResult{}, err
+	}
+
+	url := c.formatTradeURL(tradeSearchPath(opts.Realm, opts.League))
+	resp, err := c.post(ctx, url, body)
+	if err != nil {
+		return TradeSearchResult{}, err
+	}
+	return parseTradeSearchResponse(resp)
+}
+
+func parseTradeSearchResponse(resp string) (TradeSearchResult, error) {
+	var r TradeSearchResult
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return TradeSearchResult{}, err
+	}
+	return r, nil
+}
+
+// TradeFetchOptions contains the request parameters for fetching trade
+// listings.
+type TradeFetchOptions struct {
+	// The ID of the search which returned the listings.
+	SearchID string
+
+	// The IDs of the listings to fetch. Any number may be given, and they are
+	// requested in batches of TradeFetchBatchSize.
+	IDs []string
+}
+
+func validateTradeFetchOptions(opts TradeFetchOptions) error {
+	if opts.SearchID == "" || len(opts.IDs) == 0 {
+		return ErrInvalidTradeFetch
+	}
+	return nil
+}
+
+// TradeListing is a single item listed for sale on the trade site.
+type TradeListing struct {
+	ID      string              `json:"id"`
+	Listing TradeListingDetails `json:"listing"`
+	Item    Item                `json:"item"`
+}
+
+// TradeListingDetails describes the seller and price of a listing.
+type TradeListingDetails struct {
+	Method  string             `json:"method"`
+	Indexed time.Time          `json:"indexed"`
+	Stash   TradeStashLocation `json:"stash"`
+	Whisper string             `json:"whisper"`
+	Account TradeAccount       `json:"account"`
+	Price   *TradePrice        `json:"price"`
+}
+
+// TradeStashLocation is the stash tab and position of a listed item.
+type TradeStashLocation struct {
+	Name string `json:"name"`
+	X    int64  `json:"x"`
+	Y    int64  `json:"y"`
+}
+
+// TradeAccount is the seller of a listing.
+type TradeAccount struct {
+	Name              string             `json:"name"`
+	LastCharacterName string             `json:"lastCharacterName"`
+	Language          string             `json:"language"`
+	Online            *TradeOnlineStatus `json:"online"`
+}
+
+// TradeOnlineStatus is the online status of a seller. It is nil for offline
+// sellers.
+type TradeOnlineStatus struct {
+	League string `json:"league"`
+	Status string `json:"status"`
+}
+
+// TradePrice is the price of a listing, as reported by the trade API.
+type TradePrice struct {
+	Type     string  `json:"type"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency"`
+}
+
+// Price parses the listing's price. ErrNoPrice is returned for unpriced
+// listings.
+func (l TradeListing) Price() (Price, error) {
+	p := l.Listing.Price
+	if p == nil {
+		return Price{}, ErrNoPrice
+	}
+	return ParsePrice(p.Type + " " + strconv.FormatFloat(p.Amount, 'f', -1, 64) +
+		" " + p.Currency)
+}
+
+func (c *client) Fetch(opts TradeFetchOptions) ([]TradeListing, error) {
+	return c.FetchContext(context.Background(), opts)
+}
+
+func (c *client) FetchContext(ctx context.Context, opts TradeFetchOptions) ([]TradeListing, error) {
+	if err := validateTradeFetchOptions(opts); err != nil {
+		return nil, err
+	}
+
+	listings := make([]TradeListing, 0, len(opts.IDs))
+	for start := 0; start < len(opts.IDs); start += TradeFetchBatchSize {
+		end := start + TradeFetchBatchSize
+		if end > len(opts.IDs) {
+			end = len(opts.IDs)
+		}
+		url := fmt.Sprintf("%s/%s?query=%s", c.formatTradeURL(tradeFetchEndpoint),
+			strings.Join(opts.IDs[start:end], ","), url.QueryEscape(opts.SearchID))
+
+		resp, err := c.get(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+		batch, err := parseTradeFetchResponse(resp)
+		if err != nil {
+			return nil, err
+		}
+		listings = append(listings, batch...)
+	}
+	return listings, nil
+}
+
+// parseTradeFetchResponse decodes fetched listings. Listings which are no
+// longer available are returned as null, and are omitted.
+func parseTradeFetchResponse(resp string) ([]TradeListing, error) {
+	var r struct {
+		Result []*TradeListing `json:"result"`
+	}
+	if err := json.Unmarshal([]byte(resp), &r); err != nil {
+		return nil, err
+	}
+	listings := make([]TradeListing, 0, len(r.Result))
+	for _, l := range r.Result {
+		if l != nil {
+			listings = append(listings, *l)
+		}
+	}
+	return listings, nil
+}
@@ -0,0 +1,167 @@
+package poeapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func newTestTradeClient() client {
+	return client{
+		host:       testHost,
+		tradeHost:  testHost,
+		useSSL:     false,
+		useCache:   false,
+		limiter:    newRateLimiter(UnlimitedRate, UnlimitedRate),
+		httpClient: testClient,
+	}
+}
+
+func TestTradeQueryMarshalJSON(t *testing.T) {
+	q := NewTradeQuery().
+		WithStatus(TradeOnline).
+		WithType("Glorious Plate").
+		WithStat("explicit.stat_3299347043", TradeMin(100)).
+		WithLinks(TradeSocketFilter{Min: 6}).
+		WithPrice(TradeMax(5), CurrencyDivine)
+
+	b, err := json.Marshal(q)
+	if err != nil {
+		t.Fatalf("failed to marshal trade query: %v", err)
+	}
+	expected := `{"query":{"status":{"option":"online"},"type":"Glorious Plate",` +
+		`"stats":[{"type":"and","filters":[{"id":"explicit.stat_3299347043",` +
+		`"value":{"min":100},"disabled":false}]}],"filters":{"socket_filters":` +
+		`{"filters":{"links":{"min":6}}},"trade_filters":{"filters":{"price":` +
+		`{"max":5,"option":"divine"}}}}},"sort":{"price":"asc"}}`
+	if string(b) != expected {
+		t.Fatalf("unexpected trade query: expected %s, got %s", expected, b)
+	}
+}
+
+func TestEmptyTradeQueryMarshalJSON(t *testing.T) {
+	b, err := json.Marshal(NewTradeQuery())
+	if err != nil {
+		t.Fatalf("failed to marshal trade query: %v", err)
+	}
+	expected := `{"query":{"status":{"option":"online"},"stats":[]},"sort":{"price":"asc"}}`
+	if string(b) != expected {
+		t.Fatalf("unexpected trade query: expected %s, got %s", expected, b)
+	}
+}
+
+func TestValidateTradeSearchOptions(t *testing.T) {
+	cases := []struct {
+		opts     TradeSearchOptions
+		expected error
+	}{
+		{TradeSearchOptions{League: "Standard", Query: NewTradeQuery()}, nil},
+		{TradeSearchOptions{League: "Standard", Realm: "xbox", Query: NewTradeQuery()}, nil},
+		{TradeSearchOptions{League: "Standard"}, ErrInvalidTradeSearch},
+		{TradeSearchOptions{Query: NewTradeQuery()}, ErrInvalidTradeSearch},
+		{TradeSearchOptions{League: "Standard", Realm: "testrealm", Query: NewTradeQuery()}, ErrInvalidRealm},
+	}
+	for _, c := range cases {
+		if err := validateTradeSearchOptions(c.opts); err != c.expected {
+			t.Fatalf("unexpected validation result for %+v: expected %v, got %v",
+				c.opts, c.expected, err)
+		}
+	}
+}
+
+func TestTradeSearchPath(t *testing.T) {
+	cases := map[[2]string]string{
+		{"", "Standard"}:     "/api/trade/search/Standard",
+		{"pc", "Standard"}:   "/api/trade/search/Standard",
+		{"sony", "Hardcore"}: "/api/trade/search/sony/Hardcore",
+		{"", "SSF Standard"}: "/api/trade/search/SSF%20Standard",
+	}
+	for args, expected := range cases {
+		if path := tradeSearchPath(args[0], args[1]); path != expected {
+			t.Fatalf("unexpected trade search path: expected %s, got %s",
+				expected, path)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	c := newTestTradeClient()
+	result, err := c.Search(TradeSearchOptions{
+		League: "Standard",
+		Query:  NewTradeQuery().WithName("Kaom's Heart"),
+	})
+	if err != nil {
+		t.Fatalf("failed to search trade listings: %v", err)
+	}
+	if result.ID != "Ab3LSL" || result.Total != 3 || len(result.Result) != 3 {
+		t.Fatalf("unexpected search result: %+v", result)
+	}
+}
+
+func TestSearchWithInvalidOptions(t *testing.T) {
+	c := newTestTradeClient()
+	if _, err := c.Search(TradeSearchOptions{League: "Standard"}); err != ErrInvalidTradeSearch {
+		t.Fatal("failed to detect missing trade query")
+	}
+}
+
+func TestFetch(t *testing.T) {
+	c := newTestTradeClient()
+	ids := make([]string, 0, 23)
+	for i := 0; i < 23; i++ {
+		ids = append(ids, fmt.Sprintf("listing%d", i))
+	}
+	ids[12] = missingListingID
+
+	listings, err := c.Fetch(TradeFetchOptions{SearchID: "Ab3LSL", IDs: ids})
+	if err != nil {
+		t.Fatalf("failed to fetch trade listings: %v", err)
+	}
+	if len(listings) != 22 {
+		t.Fatalf("unexpected listing count: expected 22, got %d", len(listings))
+	}
+	if listings[0].ID != "listing0" || listings[21].ID != "listing22" {
+		t.Fatalf("unexpected listing order: %s, %s", listings[0].ID,
+			listings[21].ID)
+	}
+	if listings[0].Item.Name != "Kaom's Heart" ||
+		listings[0].Listing.Account.Online == nil {
+		t.Fatalf("unexpected listing: %+v", listings[0])
+	}
+}
+
+func TestFetchWithInvalidOptions(t *testing.T) {
+	c := newTestTradeClient()
+	if _, err := c.Fetch(TradeFetchOptions{IDs: []string{"a"}}); err != ErrInvalidTradeFetch {
+		t.Fatal("failed to detect missing search id")
+	}
+	if _, err := c.Fetch(TradeFetchOptions{SearchID: "Ab3LSL"}); err != ErrInvalidTradeFetch {
+		t.Fatal("failed to detect missing listing ids")
+	}
+}
+
+func TestTradeListingPrice(t *testing.T) {
+	l := TradeListing{Listing: TradeListingDetails{
+		Price: &TradePrice{Type: "~b/o", Amount: 2.5, Currency: "chaos"},
+	}}
+	p, err := l.Price()
+	if err != nil {
+		t.Fatalf("failed to parse listing price: %v", err)
+	}
+	if p.Amount != 2.5 || p.Currency != CurrencyChaos {
+		t.Fatalf("unexpected listing price: %+v", p)
+	}
+	if _, err := (TradeListing{}).Price(); err != ErrNoPrice {
+		t.Fatal("failed to detect unpriced listing")
+	}
+}
+
+func TestParseTradeFetchResponseWithInvalidJSON(t *testing.T) {
+	resp, err := loadFixture("fixtures/invalid.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if _, err := parseTradeFetchResponse(resp); err == nil {
+		t.Fatal("failed to detect invalid json")
+	}
+}
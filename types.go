@@ -1,9 +1,15 @@
 package poeapi
 
-import "time"
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
 
 const (
 	testHost = "127.0.0.1:8000"
+
+	defaultRealm = "pc"
 )
 
 var (
@@ -74,13 +80,62 @@ type LadderEntry struct {
 	Account       Account   `json:"account"`
 }
 
-// Character represents a player in a ladder entry.
+// Character represents a player in a ladder entry or on an account. Ladder
+// entries only include the name, level, class, ID, and experience.
 type Character struct {
 	Name       string `json:"name"`
 	Level      int    `json:"level"`
 	Class      string `json:"class"`
 	ID         string `json:"id"`
 	Experience int    `json:"experience"`
+	Realm      string `json:"realm"`
+	League     string `json:"league"`
+	Current    bool   `json:"current"`
+	Deleted    bool   `json:"deleted"`
+	Expired    bool   `json:"expired"`
+
+	// The following fields are only included when retrieving a single
+	// character.
+	Equipment []Item    `json:"equipment"`
+	Inventory []Item    `json:"inventory"`
+	Jewels    []Item    `json:"jewels"`
+	Passives  *Passives `json:"passives"`
+}
+
+// Passives represents the passive skill allocations of a character.
+type Passives struct {
+	// The hashes of allocated passive skills, including ascendancy skills.
+	Hashes []int `json:"hashes"`
+
+	// The hashes of allocated passive skills in cluster jewels.
+	HashesEx []int `json:"hashes_ex"`
+
+	// The chosen effect of each allocated mastery, keyed by mastery hash.
+	MasteryEffects map[string]int `json:"mastery_effects"`
+
+	// Jewels socketed in the tree, keyed by their index in Character.Jewels.
+	JewelData map[string]PassiveJewel `json:"jewel_data"`
+
+	BanditChoice        string `json:"bandit_choice"`
+	PantheonMajor       string `json:"pantheon_major"`
+	PantheonMinor       string `json:"pantheon_minor"`
+	AlternateAscendancy string `json:"alternate_ascendancy"`
+
+	// The character's ascendancy class, such as "Inquisitor". This is not
+	// part of the API response, and is only set by GetPassives.
+	Ascendancy string `json:"-"`
+}
+
+// PassiveJewel describes a jewel socketed in the passive tree.
+type PassiveJewel struct {
+	Type         string `json:"type"`
+	Radius       int    `json:"radius"`
+	RadiusMin    int    `json:"radiusMin"`
+	RadiusVisual string `json:"radiusVisual"`
+
+	// Cluster jewels add their own nodes to the tree. The subgraph describes
+	// those nodes and is left undecoded.
+	Subgraph json.RawMessage `json:"subgraph"`
 }
 
 // Account represents an account for a ladder entry.
@@ -129,45 +184,215 @@ type Stash struct {
 	LastCharacterName string `json:"lastCharacterName"`
 	Index             string `json:"stash"`
 	Type              string `json:"stashType"`
+	League            string `json:"league"`
 	Items             []Item `json:"items"`
 	Public            bool   `json:"public"`
 }
 
+// FrameType identifies the rarity or kind of an item, which determines the
+// frame drawn around it in game.
+type FrameType int64
+
+// Item frame types.
+const (
+	FrameNormal FrameType = iota
+	FrameMagic
+	FrameRare
+	FrameUnique
+	FrameGem
+	FrameCurrency
+	FrameDivinationCard
+	FrameQuest
+	FrameProphecy
+	FrameFoil
+	FrameSupporterFoil
+)
+
+var frameTypeNames = map[FrameType]string{
+	FrameNormal:         "Normal",
+	FrameMagic:          "Magic",
+	FrameRare:           "Rare",
+	FrameUnique:         "Unique",
+	FrameGem:            "Gem",
+	FrameCurrency:       "Currency",
+	FrameDivinationCard: "Divination Card",
+	FrameQuest:          "Quest",
+	FrameProphecy:       "Prophecy",
+	FrameFoil:           "Foil",
+	FrameSupporterFoil:  "Supporter Foil",
+}
+
+// String returns the name of the frame type, such as "Unique".
+func (f FrameType) String() string {
+	if name, ok := frameTypeNames[f]; ok {
+		return name
+	}
+	return "FrameType(" + strconv.FormatInt(int64(f), 10) + ")"
+}
+
+// Frame returns the item's frame type.
+func (i Item) Frame() FrameType {
+	return FrameType(i.FrameType)
+}
+
 // Item represents an item listing in the stash tab API.
 type Item struct {
-	Corrupted         bool          `json:"corrupted"`
-	ExplicitMods      []string      `json:"explicitMods"`
-	FlavorText        []string      `json:"flavourText"`
-	FrameType         int64         `json:"frameType"`
-	Height            int64         `json:"h"`
-	ID                string        `json:"id"`
-	Icon              string        `json:"icon"`
-	Identified        bool          `json:"identified"`
-	ImplicitMods      []string      `json:"implicitMods"`
-	InventoryID       string        `json:"inventoryId"`
-	ItemLevel         int64         `json:"ilvl"`
-	League            string        `json:"league"`
-	LockedToCharacter bool          `json:"lockedToCharacter"`
-	Name              string        `json:"name"`
-	Note              string        `json:"note"`
-	Properties        []Property    `json:"properties"`
-	Requirements      []Requirement `json:"requirements"`
-	SocketedItems     interface{}   `json:"socketedItems"`
-	Sockets           []Socket      `json:"sockets"`
-	Support           bool          `json:"support"`
-	TalismanTier      int64         `json:"talismanTier"`
-	TypeLine          string        `json:"typeLine"`
-	Verified          bool          `json:"verified"`
-	Width             int64         `json:"w"`
-	XPosition         int64         `json:"x"`
-	YPosition         int64         `json:"y"`
-}
-
-// Property represents a property of an Item.
+	AbyssJewel           bool                `json:"abyssJewel"`
+	AdditionalProperties []Property          `json:"additionalProperties"`
+	BaseType             string              `json:"baseType"`
+	Category             map[string][]string `json:"category"`
+	Corrupted            bool                `json:"corrupted"`
+	CosmeticMods         []string            `json:"cosmeticMods"`
+	CraftedMods          []string            `json:"craftedMods"`
+	DescrText            string              `json:"descrText"`
+	Duplicated           bool                `json:"duplicated"`
+	Elder                bool                `json:"elder"`
+	EnchantMods          []string            `json:"enchantMods"`
+	ExplicitMods         []string            `json:"explicitMods"`
+	Extended             *ItemExtended       `json:"extended"`
+	FlavorText           []string            `json:"flavourText"`
+	Fractured            bool                `json:"fractured"`
+	FracturedMods        []string            `json:"fracturedMods"`
+	FrameType            int64               `json:"frameType"`
+	Height               int64               `json:"h"`
+	Hybrid               *ItemHybrid         `json:"hybrid"`
+	ID                   string              `json:"id"`
+	Icon                 string              `json:"icon"`
+	Identified           bool                `json:"identified"`
+	ImplicitMods         []string            `json:"implicitMods"`
+	IncubatedItem        *IncubatedItem      `json:"incubatedItem"`
+	Influences           Influences          `json:"influences"`
+	InventoryID          string              `json:"inventoryId"`
+	ItemLevel            int64               `json:"ilvl"`
+	League               string              `json:"league"`
+	LockedToCharacter    bool                `json:"lockedToCharacter"`
+	Mirrored             bool                `json:"mirrored"`
+	Name                 string              `json:"name"`
+	Note                 string              `json:"note"`
+	Properties           []Property          `json:"properties"`
+	Rarity               string              `json:"rarity"`
+	Requirements         []Requirement       `json:"requirements"`
+	SecDescrText         string              `json:"secDescrText"`
+	Shaper               bool                `json:"shaper"`
+	SocketedItems        []Item              `json:"socketedItems"`
+	Sockets              []Socket            `json:"sockets"`
+	Split                bool                `json:"split"`
+	StackSize            int64               `json:"stackSize"`
+	MaxStackSize         int64               `json:"maxStackSize"`
+	Support              bool                `json:"support"`
+	Synthesised          bool                `json:"synthesised"`
+	TalismanTier         int64               `json:"talismanTier"`
+	TypeLine             string              `json:"typeLine"`
+	UtilityMods          []string            `json:"utilityMods"`
+	Verified             bool                `json:"verified"`
+	Width                int64               `json:"w"`
+	XPosition            int64               `json:"x"`
+	YPosition            int64               `json:"y"`
+
+	// The index of the socket containing this item, for items in
+	// SocketedItems.
+	Socket int64 `json:"socket"`
+}
+
+// Influences represents the influences on an item. Older items may instead
+// set the Shaper and Elder fields of Item.
+type Influences struct {
+	Shaper   bool `json:"shaper"`
+	Elder    bool `json:"elder"`
+	Crusader bool `json:"crusader"`
+	Redeemer bool `json:"redeemer"`
+	Hunter   bool `json:"hunter"`
+	Warlord  bool `json:"warlord"`
+}
+
+// ItemExtended contains additional item details which are included by some
+// endpoints, such as the trade API.
+type ItemExtended struct {
+	Category      string   `json:"category"`
+	Subcategories []string `json:"subcategories"`
+	Prefixes      int      `json:"prefixes"`
+	Suffixes      int      `json:"suffixes"`
+	Text          string   `json:"text"`
+
+	// Details of each mod, keyed by mod type such as "explicit".
+	Mods map[string][]ItemModInfo `json:"mods"`
+
+	// The stats of each mod line, keyed by mod type such as "explicit".
+	Hashes map[string][]ItemModHash `json:"hashes"`
+}
+
+// ItemModInfo describes a single mod on an item.
+type ItemModInfo struct {
+	Name       string         `json:"name"`
+	Tier       string         `json:"tier"`
+	Level      int            `json:"level"`
+	Magnitudes []ModMagnitude `json:"magnitudes"`
+}
+
+// ModMagnitude is the range of a stat granted by a mod.
+type ModMagnitude struct {
+	Hash string  `json:"hash"`
+	Min  float64 `json:"min"`
+	Max  float64 `json:"max"`
+}
+
+// ItemModHash links a stat to the mod lines which grant it. The API encodes
+// these as tuples, such as ["explicit.stat_3299347043", [0]], where the list
+// holds the indexes of the item's mod lines of the same type which grant the
+// stat. Hybrid mod lines are listed by several hashes.
+type ItemModHash struct {
+	StatID string
+	Mods   []int
+}
+
+// UnmarshalJSON decodes an ItemModHash from its tuple form.
+func (h *ItemModHash) UnmarshalJSON(b []byte) error {
+	var tuple []json.RawMessage
+	if err := json.Unmarshal(b, &tuple); err != nil {
+		return err
+	}
+	if len(tuple) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(tuple[0], &h.StatID); err != nil {
+		return err
+	}
+	if len(tuple) > 1 && string(tuple[1]) != "null" {
+		return json.Unmarshal(tuple[1], &h.Mods)
+	}
+	return nil
+}
+
+// MarshalJSON encodes an ItemModHash in its tuple form.
+func (h ItemModHash) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{h.StatID, h.Mods})
+}
+
+// ItemHybrid describes the second form of a hybrid gem, such as the Vaal
+// skill of a Vaal gem.
+type ItemHybrid struct {
+	IsVaalGem    bool       `json:"isVaalGem"`
+	BaseTypeName string     `json:"baseTypeName"`
+	Properties   []Property `json:"properties"`
+	ExplicitMods []string   `json:"explicitMods"`
+	SecDescrText string     `json:"secDescrText"`
+}
+
+// IncubatedItem describes the reward of an incubator applied to an item.
+type IncubatedItem struct {
+	Name     string `json:"name"`
+	Level    int    `json:"level"`
+	Progress int    `json:"progress"`
+	Total    int    `json:"total"`
+}
+
+// Property represents a property of an Item, such as its quality or armour.
 type Property struct {
 	DisplayMode int64           `json:"displayMode"`
 	Name        string          `json:"name"`
 	Values      [][]interface{} `json:"values"`
+	Type        int64           `json:"type"`
+	Progress    float64         `json:"progress"`
 }
 
 // Requirement represents an attribute requirement on an Item, such as strength.
@@ -175,10 +400,15 @@ type Requirement struct {
 	DisplayMode int64           `json:"displayMode"`
 	Name        string          `json:"name"`
 	Values      [][]interface{} `json:"values"`
+	Suffix      string          `json:"suffix"`
 }
 
 // Socket represents a socket on an item, including its color and links.
 type Socket struct {
 	Attribute string `json:"attr"`
 	Group     int64  `json:"group"`
+
+	// The colour of the socket: R, G, B, W (white), A (abyss), or DV
+	// (delve).
+	Colour string `json:"sColour"`
 }
@@ -0,0 +1,141 @@
+package poeapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseStashItemModel(t *testing.T) {
+	resp, err := loadFixture("fixtures/stash.json")
+	if err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	stashes, err := parseStashResponse(resp)
+	if err != nil {
+		t.Fatalf("failed to parse stash response: %v", err)
+	}
+
+	var gem, gloves *Item
+	for i := range stashes.Stashes {
+		for j := range stashes.Stashes[i].Items {
+			item := &stashes.Stashes[i].Items[j]
+			switch {
+			case item.Hybrid != nil && gem == nil:
+				gem = item
+			case item.Name == "Command of the Pit":
+				gloves = item
+			}
+		}
+	}
+
+	if gem == nil {
+		t.Fatal("failed to find hybrid gem in fixture")
+	}
+	if gem.Frame() != FrameGem {
+		t.Fatalf("failed to decode frame type. expected %v, got %v",
+			FrameGem, gem.Frame())
+	}
+	if !gem.Hybrid.IsVaalGem || gem.Hybrid.BaseTypeName != "Reave" {
+		t.Fatalf("failed to decode hybrid gem: %+v", gem.Hybrid)
+	}
+	if len(gem.Category["gems"]) != 1 || gem.Category["gems"][0] != "activegem" {
+		t.Fatalf("failed to decode category: %v", gem.Category)
+	}
+
+	if gloves == nil {
+		t.Fatal("failed to find gloves in fixture")
+	}
+	if len(gloves.Sockets) != 4 {
+		t.Fatalf("failed to decode sockets: %+v", gloves.Sockets)
+	}
+	if gloves.Sockets[0].Colour != "W" || gloves.Sockets[2].Colour != "A" {
+		t.Fatalf("failed to decode socket colours: %+v", gloves.Sockets)
+	}
+}
+
+func TestParseSocketedItems(t *testing.T) {
+	b := []byte(`{
+		"typeLine": "Vaal Regalia",
+		"frameType": 2,
+		"influences": {"shaper": true, "hunter": true},
+		"stackSize": 1,
+		"socketedItems": [
+			{"typeLine": "Arc", "frameType": 4, "socket": 1, "support": false}
+		]
+	}`)
+	var item Item
+	if err := json.Unmarshal(b, &item); err != nil {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+	if len(item.SocketedItems) != 1 {
+		t.Fatalf("failed to decode socketed items: %+v", item.SocketedItems)
+	}
+	gem := item.SocketedItems[0]
+	if gem.TypeLine != "Arc" || gem.Frame() != FrameGem || gem.Socket != 1 {
+		t.Fatalf("failed to decode socketed gem: %+v", gem)
+	}
+	if !item.Influences.Shaper || !item.Influences.Hunter || item.Influences.Elder {
+		t.Fatalf("failed to decode influences: %+v", item.Influences)
+	}
+}
+
+func TestParseItemExtended(t *testing.T) {
+	b := []byte(`{
+		"category": "armour",
+		"subcategories": ["chest"],
+		"prefixes": 2,
+		"suffixes": 1,
+		"mods": {
+			"explicit": [
+				{"name": "Robust", "tier": "P1", "level": 83,
+				 "magnitudes": [{"hash": "explicit.stat_3299347043", "min": 100, "max": 119}]}
+			]
+		},
+		"hashes": {
+			"explicit": [["explicit.stat_3299347043", [0]], ["explicit.stat_123", null]]
+		}
+	}`)
+	var ext ItemExtended
+	if err := json.Unmarshal(b, &ext); err != nil {
+		t.Fatalf("failed to decode extended item data: %v", err)
+	}
+	hashes := ext.Hashes["explicit"]
+	if len(hashes) != 2 {
+		t.Fatalf("failed to decode hashes: %+v", hashes)
+	}
+	if hashes[0].StatID != "explicit.stat_3299347043" || len(hashes[0].Mods) != 1 {
+		t.Fatalf("failed to decode hash tuple: %+v", hashes[0])
+	}
+	if hashes[1].Mods != nil {
+		t.Fatalf("failed to decode hash without mods: %+v", hashes[1])
+	}
+	if ext.Mods["explicit"][0].Magnitudes[0].Max != 119 {
+		t.Fatalf("failed to decode mod magnitudes: %+v", ext.Mods)
+	}
+
+	out, err := json.Marshal(hashes[0])
+	if err != nil {
+		t.Fatalf("failed to encode hash: %v", err)
+	}
+	if string(out) != `["explicit.stat_3299347043",[0]]` {
+		t.Fatalf("failed to encode hash tuple. got %s", out)
+	}
+}
+
+func TestFrameTypeString(t *testing.T) {
+	cases := map[FrameType]string{
+		FrameNormal:         "Normal",
+		FrameUnique:         "Unique",
+		FrameDivinationCard: "Divination Card",
+		FrameType(99):       "FrameType(99)",
+	}
+	for frame, expected := range cases {
+		if frame.String() != expected {
+			t.Fatalf("failed to format frame type. expected %s, got %s",
+				expected, frame.String())
+		}
+	}
+	if (Item{FrameType: 3}).Frame() != FrameUnique {
+		t.Fatal("failed to get item frame type")
+	}
+}
@@ -1,9 +1,11 @@
 package poeapi
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"runtime"
@@ -13,9 +15,18 @@ import (
 )
 
 const (
-	repo              = "github.com/willroberts/poeapi"
-	rateLimitEndpoint = "/rate-limit-me"
-	failureEndpoint   = "/fail-me"
+	repo               = "github.com/willroberts/poeapi"
+	rateLimitEndpoint  = "/rate-limit-me"
+	failureEndpoint    = "/fail-me"
+	policyEndpoint     = "/rate-policy"
+	tokenEndpoint      = "/oauth/token"
+	tradeLeaguePath    = "/api/trade/search/Standard"
+	exchangeLeaguePath = "/api/trade/exchange/Standard"
+	missingListingID   = "missing"
+	privateAccount     = "Private#0001"
+	conditionalPath    = "/conditional"
+	conditionalETag    = `"v1"`
+	conditionalModTime = "Sun, 20 Aug 2023 12:00:00 GMT"
 )
 
 var (
@@ -58,6 +69,12 @@ type testHandler struct {
 	pvpMatchesFixture   string
 	stashFixture        string
 	latestChangeFixture string
+	charactersFixture   string
+	characterFixture    string
+	publicCharsFixture  string
+	tradeSearchFixture  string
+	tradeListingFixture string
+	exchangeFixture     string
 }
 
 func newTestHandler() (testHandler, error) {
@@ -102,10 +119,44 @@ func newTestHandler() (testHandler, error) {
 		return testHandler{}, err
 	}
 	h.latestChangeFixture = f
+	f, err = loadFixture("fixtures/characters.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.charactersFixture = f
+	f, err = loadFixture("fixtures/character.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.characterFixture = f
+	f, err = loadFixture("fixtures/public_characters.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.publicCharsFixture = f
+	f, err = loadFixture("fixtures/trade-search.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.tradeSearchFixture = f
+	f, err = loadFixture("fixtures/trade-fetch.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.tradeListingFixture = f
+	f, err = loadFixture("fixtures/exchange.json")
+	if err != nil {
+		return testHandler{}, err
+	}
+	h.exchangeFixture = f
 	return h, nil
 }
 
 func (h testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.URL.Path, tradeFetchEndpoint+"/") {
+		h.serveTradeFetch(w, r)
+		return
+	}
 	switch r.URL.Path {
 	case "/ladders/Standard":
 		w.Write([]byte(h.ladderFixture))
@@ -121,10 +172,36 @@ func (h testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte(h.pvpMatchesFixture))
 	case "/public-stash-tabs":
 		w.Write([]byte(h.stashFixture))
+	case "/character", "/character/xbox":
+		w.Write([]byte(h.charactersFixture))
+	case "/character/Character1":
+		w.Write([]byte(h.characterFixture))
+	case publicCharactersEndpoint:
+		if r.URL.Query().Get("accountName") == privateAccount {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(h.publicCharsFixture))
 	case "/api/Data/GetStats":
 		w.Write([]byte(h.latestChangeFixture))
 	case rateLimitEndpoint:
+		w.Header().Set("Retry-After", "2")
 		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":3,"message":"Rate limit exceeded"}}`))
+	case policyEndpoint:
+		w.Header().Set("X-Rate-Limit-Policy", "test-policy")
+		w.Header().Set("X-Rate-Limit-Rules", "Ip")
+		w.Header().Set("X-Rate-Limit-Ip", "5:10:60,15:60:300")
+		w.Header().Set("X-Rate-Limit-Ip-State", "1:10:0,1:60:0")
+		w.Write([]byte("{}"))
+	case tokenEndpoint:
+		serveToken(w, r)
+	case tradeLeaguePath:
+		h.serveTradeSearch(w, r)
+	case exchangeLeaguePath:
+		h.serveExchange(w, r)
+	case conditionalPath:
+		serveConditional(w, r)
 	case failureEndpoint:
 		w.WriteHeader(http.StatusInternalServerError)
 	default:
@@ -132,6 +209,97 @@ func (h testHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// serveConditional serves a response which must be revalidated before each
+// use, with the validator named by the "validator" query parameter: "etag",
+// "modified", or both by default.
+func serveConditional(w http.ResponseWriter, r *http.Request) {
+	validator := r.URL.Query().Get("validator")
+	w.Header().Set("Cache-Control", "no-cache")
+	if validator != "modified" {
+		w.Header().Set("ETag", conditionalETag)
+	}
+	if validator != "etag" {
+		w.Header().Set("Last-Modified", conditionalModTime)
+	}
+	if r.Header.Get("If-None-Match") == conditionalETag ||
+		r.Header.Get("If-Modified-Since") == conditionalModTime {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Write([]byte(`{"conditional":true}`))
+}
+
+// serveToken imitates the OAuth token endpoint. Requests with the client ID
+// "test" succeed, and all other requests are rejected.
+func serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("client_id") != "test" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_client","error_description":"unknown client"}`))
+		return
+	}
+	token := r.PostForm.Get("grant_type") + "-token"
+	if code := r.PostForm.Get("code"); code != "" {
+		token = code + "-token"
+	}
+	w.Write([]byte(fmt.Sprintf(`{"access_token":%q,"expires_in":3600,`+
+		`"token_type":"bearer","scope":%q,"refresh_token":"refresh"}`,
+		token, r.PostForm.Get("scope"))))
+}
+
+// serveTradeSearch imitates the trade search endpoint, which only accepts
+// POST requests with a query.
+func (h testHandler) serveTradeSearch(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query *json.RawMessage `json:"query"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil ||
+		body.Query == nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":2,"message":"Invalid query"}}`))
+		return
+	}
+	w.Write([]byte(h.tradeSearchFixture))
+}
+
+// serveExchange imitates the bulk exchange endpoint, which requires the wanted
+// and offered currencies.
+func (h testHandler) serveExchange(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Query struct {
+			Have []string `json:"have"`
+			Want []string `json:"want"`
+		} `json:"query"`
+	}
+	if r.Method != http.MethodPost || json.NewDecoder(r.Body).Decode(&body) != nil ||
+		len(body.Query.Have) == 0 || len(body.Query.Want) == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":2,"message":"Invalid query"}}`))
+		return
+	}
+	w.Write([]byte(h.exchangeFixture))
+}
+
+// serveTradeFetch imitates the trade fetch endpoint, returning a listing for
+// each requested ID. The ID "missing" is returned as null, as for listings
+// which are no longer available.
+func (h testHandler) serveTradeFetch(w http.ResponseWriter, r *http.Request) {
+	ids := strings.Split(strings.TrimPrefix(r.URL.Path, tradeFetchEndpoint+"/"), ",")
+	if len(ids) > TradeFetchBatchSize || r.URL.Query().Get("query") == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":{"code":2,"message":"Invalid query"}}`))
+		return
+	}
+	listings := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == missingListingID {
+			listings = append(listings, "null")
+			continue
+		}
+		listings = append(listings, strings.ReplaceAll(h.tradeListingFixture, "LISTING_ID", id))
+	}
+	w.Write([]byte(`{"result":[` + strings.Join(listings, ",") + `]}`))
+}
+
 func startStubServer() error {
 	h, err := newTestHandler()
 	if err != nil {
@@ -143,9 +311,14 @@ func startStubServer() error {
 		ReadTimeout:  testTimeout,
 		WriteTimeout: testTimeout,
 	}
+	// Listen before returning so that tests never race the server startup.
+	l, err := net.Listen("tcp", testHost)
+	if err != nil {
+		return err
+	}
 	go func() {
 		log.Println("starting local http server")
-		if err := s.ListenAndServe(); err != nil {
+		if err := s.Serve(l); err != nil {
 			log.Println("http test server error:", err)
 		}
 	}()